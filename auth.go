@@ -0,0 +1,303 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// AuthenticateMethod is the method clients call to present their token when
+// ServerConfig.Auth is set. Until a token is accepted, every other request
+// is refused with Unauthenticated:
+//
+//	→ {"jsonrpc":"2.0","method":"authenticate","params":{"token":"3f9c…"},"id":1}
+//	← {"jsonrpc":"2.0","result":{"principal":"owner"},"id":1}
+//
+// Instead of calling it, a client may put the token in the "meta" member of
+// its first request (see MetaAuthToken). With Auth configured, the server
+// handles this method itself, even if a handler is registered under the
+// same name.
+const AuthenticateMethod = "authenticate"
+
+// MetaAuthToken is the "meta" key carrying a token on a request sent before
+// the connection is authenticated:
+//
+//	{"jsonrpc":"2.0","method":"status","id":1,"meta":{"authToken":"3f9c…"}}
+const MetaAuthToken = "authToken"
+
+// DefaultAuthTimeout is how long a client has to authenticate when
+// AuthConfig.Timeout is zero.
+const DefaultAuthTimeout = 10 * time.Second
+
+// OwnerPrincipal is the name of the principal of the token the server
+// generates at Start.
+const OwnerPrincipal = "owner"
+
+// AuthConfig requires clients to present a token before any other request,
+// for sockets on machines shared by several users. At Start the server
+// generates a random token and writes it to a file only its owner can read;
+// clients read the file and present the token.
+type AuthConfig struct {
+	// TokenFile is where the server writes its generated token at Start,
+	// with mode 0600. The file is removed at Stop.
+	// If empty, it is the socket path plus ".token" for Unix sockets (see
+	// AuthTokenPath). For other addresses the token is not written, and the
+	// application passes Server.AuthToken to its clients itself.
+	TokenFile string
+
+	// Tokens are further tokens the server accepts, with the principal each
+	// one authenticates as, such as read-only tokens handed to CI jobs.
+	// Optional.
+	Tokens map[string]Principal
+
+	// Timeout closes connections that have not authenticated in time.
+	// If zero, DefaultAuthTimeout is used; negative disables the timeout.
+	Timeout time.Duration
+}
+
+// timeout returns the effective authentication timeout.
+func (ac *AuthConfig) timeout() time.Duration {
+	if ac.Timeout == 0 {
+		return DefaultAuthTimeout
+	}
+	return ac.Timeout
+}
+
+// Principal is the identity a connection authenticated as.
+type Principal struct {
+	// Name identifies the principal in logs and authorization rules.
+	// The generated token authenticates as OwnerPrincipal.
+	Name string
+
+	// Roles are free-form labels for authorization, such as "read-only".
+	Roles []string
+}
+
+// HasRole reports whether the principal has role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && contains(p.Roles, role)
+}
+
+// authenticateParams are the params of AuthenticateMethod.
+type authenticateParams struct {
+	Token string `json:"token"`
+}
+
+// authenticateResult is the result of AuthenticateMethod.
+type authenticateResult struct {
+	Principal string   `json:"principal"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// AuthTokenPath returns the default AuthConfig.TokenFile for a socket path:
+// the socket file plus ".token". It returns "" for named pipes, abstract
+// sockets and network addresses, which have no file to put it next to.
+func AuthTokenPath(socketPath string) string {
+	if IsWindows() || isNetworkAddress(socketPath) {
+		return ""
+	}
+	path := GetSocketPath(socketPath)
+	if strings.HasPrefix(path, "@") {
+		return ""
+	}
+	return path + ".token"
+}
+
+// ReadAuthToken reads the token a server wrote to path, for clients.
+func ReadAuthToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read auth token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// AuthToken returns the token the server generated for OwnerPrincipal, or ""
+// if ServerConfig.Auth is not set or the server has not started.
+func (s *Server) AuthToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authToken
+}
+
+// setupAuth generates the owner token and writes it to the token file.
+func (s *Server) setupAuth() error {
+	auth := s.config.Auth
+	if auth == nil {
+		return nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate auth token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	path := auth.TokenFile
+	if path == "" {
+		path = AuthTokenPath(s.config.SocketPath)
+	}
+	if path != "" {
+		if err := writeTokenFile(path, token); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.authToken = token
+	s.tokenFile = path
+	s.mu.Unlock()
+	return nil
+}
+
+// writeTokenFile writes token to a new file at path that only the owner can
+// read. An existing file is replaced rather than reused, so its permissions
+// (or a symlink planted in its place) cannot leak the token.
+func writeTokenFile(path, token string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove old auth token file: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create auth token file: %w", err)
+	}
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write auth token file: %w", err)
+	}
+	return f.Close()
+}
+
+// lookupToken returns the principal token authenticates as, or nil. Every
+// comparison takes constant time, and all tokens are compared.
+func (s *Server) lookupToken(token string) *Principal {
+	var found *Principal
+
+	if owner := s.AuthToken(); owner != "" && subtle.ConstantTimeCompare([]byte(token), []byte(owner)) == 1 {
+		found = &Principal{Name: OwnerPrincipal}
+	}
+	for candidate, principal := range s.config.Auth.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 && found == nil {
+			p := principal
+			p.Roles = append([]string(nil), principal.Roles...)
+			found = &p
+		}
+	}
+
+	return found
+}
+
+// Authenticated reports whether the client has presented a valid token.
+// It is always false if ServerConfig.Auth is not set.
+func (c *Connection) Authenticated() bool {
+	return c.principal.Load() != nil
+}
+
+// Principal returns the identity the client authenticated as, or nil.
+func (c *Connection) Principal() *Principal {
+	return c.principal.Load()
+}
+
+// authRequired reports whether the connection must authenticate before
+// other requests are served.
+func (c *Connection) authRequired() bool {
+	return c.server != nil && c.server.config.Auth != nil
+}
+
+// login authenticates the connection with token.
+func (c *Connection) login(token string) (*Principal, *RPCError) {
+	principal := c.server.lookupToken(token)
+	if principal == nil {
+		c.logger.Warn("authentication failed", "remote_addr", c.remoteAddr)
+		return nil, NewUnauthenticatedError("invalid token")
+	}
+	if !c.principal.CompareAndSwap(nil, principal) {
+		return nil, NewInvalidRequestError("already authenticated")
+	}
+	if c.authTimer != nil {
+		c.authTimer.Stop()
+	}
+
+	c.logger.Debug("client authenticated", "principal", principal.Name)
+	return principal, nil
+}
+
+// checkAuth refuses requests from a connection that has not authenticated,
+// unless the request authenticates it: a call to AuthenticateMethod, or a
+// request carrying MetaAuthToken. The token is removed from the request, so
+// handlers and middleware never see it.
+func (c *Connection) checkAuth(req *Request) *RPCError {
+	if !c.authRequired() {
+		return nil
+	}
+	token, hasToken := req.Meta[MetaAuthToken].(string)
+	delete(req.Meta, MetaAuthToken)
+
+	if c.Authenticated() {
+		return nil
+	}
+	if hasToken {
+		_, err := c.login(token)
+		return err
+	}
+	if req.Method == AuthenticateMethod {
+		return nil
+	}
+	return NewUnauthenticatedError(map[string]string{"method": req.Method})
+}
+
+// startAuthTimer closes the connection if it has not authenticated within
+// the configured timeout. It must be called before the connection is served.
+func (c *Connection) startAuthTimer() {
+	if !c.authRequired() {
+		return
+	}
+	timeout := c.server.config.Auth.timeout()
+	if timeout < 0 {
+		return
+	}
+
+	c.authTimer = time.AfterFunc(timeout, func() {
+		if c.Authenticated() || c.IsClosed() {
+			return
+		}
+		c.logger.Warn("client did not authenticate in time", "timeout", timeout)
+		c.sendFinal(&Notification{
+			JSONRPC: "2.0",
+			Method:  DisconnectMethod,
+			Params:  disconnectParams{Reason: "authentication timeout"},
+		})
+		c.closeAfterFlush()
+	})
+}
+
+// authenticateHandler is the handler of AuthenticateMethod.
+func authenticateHandler(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	conn := ConnectionFromContext(ctx)
+	if conn == nil {
+		return nil, NewInternalError("no connection in context")
+	}
+	if conn.Authenticated() {
+		return nil, NewInvalidRequestError("already authenticated")
+	}
+
+	var p authenticateParams
+	if err := json.Unmarshal(params, &p); err != nil || p.Token == "" {
+		return nil, NewInvalidParamsError("token is required")
+	}
+
+	principal, err := conn.login(p.Token)
+	if err != nil {
+		return nil, err
+	}
+	return authenticateResult{Principal: principal.Name, Roles: principal.Roles}, nil
+}
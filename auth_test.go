@@ -0,0 +1,253 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// registerAuthMethods registers a "meta" method that returns the request's
+// meta, and an "authenticate" handler that is only reachable without Auth.
+func registerAuthMethods(server *Server) {
+	server.RegisterFunc("meta", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return MetaFromContext(ctx), nil
+	})
+	server.RegisterFunc(AuthenticateMethod, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "app authenticate", nil
+	})
+}
+
+// rpc sends a request over a client connection and reads the reply.
+func rpc(t *testing.T, codec *LineDelimitedCodec, method string, params interface{}) (*Message, error) {
+	t.Helper()
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := codec.WriteJSON(&Request{JSONRPC: "2.0", Method: method, Params: data, ID: 1}); err != nil {
+		return nil, err
+	}
+	var msg Message
+	if err := codec.ReadJSON(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func TestAuth_Authenticate(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{
+		SocketPath: "tcp://127.0.0.1:0",
+		Auth:       &AuthConfig{},
+	})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+	codec := NewCodec(conn)
+
+	// Refused before authenticating, without revealing which methods exist
+	for _, method := range []string{"add", "no.such.method"} {
+		msg, err := rpc(t, codec, method, map[string]int{"a": 1, "b": 2})
+		if err != nil || msg.Error == nil || msg.Error.Code != Unauthenticated {
+			t.Fatalf("%s before authenticate = %+v, %v, want Unauthenticated", method, msg, err)
+		}
+	}
+
+	msg, err := rpc(t, codec, AuthenticateMethod, map[string]string{"token": "wrong"})
+	if err != nil || msg.Error == nil || msg.Error.Code != Unauthenticated {
+		t.Fatalf("authenticate with a wrong token = %+v, %v, want Unauthenticated", msg, err)
+	}
+
+	msg, err = rpc(t, codec, AuthenticateMethod, map[string]string{"token": server.AuthToken()})
+	if err != nil || msg.Error != nil {
+		t.Fatalf("authenticate = %+v, %v", msg, err)
+	}
+	var result authenticateResult
+	if json.Unmarshal(msg.Result, &result); result.Principal != OwnerPrincipal {
+		t.Errorf("authenticate result = %v, want the owner principal", msg.Result)
+	}
+
+	if sum, err := callAdd(t, conn); err != nil || sum != 5 {
+		t.Errorf("callAdd() after authenticate = %v, %v", sum, err)
+	}
+	if infos := waitForConnections(t, server, 1); infos[0].Principal == nil || infos[0].Principal.Name != OwnerPrincipal {
+		t.Errorf("ConnectionInfo.Principal = %+v, want the owner", infos[0].Principal)
+	}
+}
+
+func TestAuth_MetaToken(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{
+		Auth: &AuthConfig{
+			Tokens: map[string]Principal{"ci-token": {Name: "ci", Roles: []string{"read-only"}}},
+		},
+	}, registerAuthMethods)
+
+	resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"meta","id":1,"meta":{"authToken":"ci-token","locale":"de"}}`)
+	if errResp != nil {
+		t.Fatalf("meta with a token error = %+v", errResp.Error)
+	}
+	// The token is not passed on to handlers
+	if meta, _ := resp.Result.(Meta); meta[MetaAuthToken] != nil || meta["locale"] != "de" {
+		t.Errorf("Handler meta = %v, want the locale only", resp.Result)
+	}
+
+	principal := conn.Principal()
+	if !conn.Authenticated() || principal.Name != "ci" || !principal.HasRole("read-only") {
+		t.Errorf("Principal() = %+v, want ci with the read-only role", principal)
+	}
+
+	// Once authenticated, authenticate is refused
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"authenticate","params":{"token":"ci-token"},"id":2}`); errResp == nil || errResp.Error.Code != InvalidRequest {
+		t.Errorf("Second authenticate = %+v, want InvalidRequest", errResp)
+	}
+}
+
+func TestAuth_WrongMetaToken(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{Auth: &AuthConfig{}}, registerAuthMethods)
+
+	// Before Start there is no generated token, so no token is valid
+	_, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"meta","id":1,"meta":{"authToken":""}}`)
+	if errResp == nil || errResp.Error.Code != Unauthenticated {
+		t.Fatalf("meta with an empty token = %+v, want Unauthenticated", errResp)
+	}
+	if conn.Authenticated() || conn.Principal() != nil {
+		t.Error("Connection authenticated with an invalid token")
+	}
+}
+
+func TestAuth_BeforeHandshake(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{
+		Auth:      &AuthConfig{Tokens: map[string]Principal{"secret": {Name: "app"}}},
+		Handshake: &HandshakeConfig{},
+		Lifecycle: &LifecycleConfig{
+			Methods: map[ConnectionState][]string{StateUninitialized: {InitializeMethod}},
+		},
+	}, registerAuthMethods)
+
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":1}`); errResp == nil || errResp.Error.Code != Unauthenticated {
+		t.Fatalf("initialize before authenticate = %+v, want Unauthenticated", errResp)
+	}
+	resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"authenticate","params":{"token":"secret"},"id":2}`)
+	if errResp != nil {
+		t.Fatalf("authenticate before initialize error = %+v", errResp.Error)
+	}
+	if result, ok := resp.Result.(authenticateResult); !ok || result.Principal != "app" {
+		t.Errorf("authenticate result = %#v, want principal app", resp.Result)
+	}
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":3}`); errResp != nil {
+		t.Fatalf("initialize after authenticate error = %+v", errResp.Error)
+	}
+}
+
+func TestAuth_Disabled(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{}, registerAuthMethods)
+
+	// A registered "authenticate" handler is an ordinary method
+	if resp, _ := call(t, conn, `{"jsonrpc":"2.0","method":"authenticate","id":1}`); resp == nil || resp.Result != "app authenticate" {
+		t.Errorf("authenticate without auth = %+v, want the registered handler", resp)
+	}
+	if conn.Authenticated() || conn.Principal() != nil {
+		t.Error("Connection without auth reports a principal")
+	}
+}
+
+func TestAuth_Timeout(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{
+		SocketPath: "tcp://127.0.0.1:0",
+		Auth:       &AuthConfig{Timeout: 50 * time.Millisecond},
+	})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	codec := NewCodec(conn)
+
+	var msg Message
+	if err := codec.ReadJSON(&msg); err != nil || msg.Method != DisconnectMethod {
+		t.Fatalf("ReadJSON() = %+v, %v, want a disconnect notification", msg, err)
+	}
+	if err := codec.ReadJSON(&msg); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadJSON() after the timeout error = %v, want EOF", err)
+	}
+	waitForConnections(t, server, 0)
+}
+
+func TestAuth_TokenFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Token files are written next to Unix sockets")
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "auth.sock")
+	tokenPath := AuthTokenPath(socketPath)
+	if tokenPath != socketPath+".token" {
+		t.Fatalf("AuthTokenPath() = %q, want the socket path plus .token", tokenPath)
+	}
+	// A stale file is replaced, not reused
+	if err := os.WriteFile(tokenPath, []byte("stale"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	server, err := NewServer(ServerConfig{SocketPath: socketPath, Auth: &AuthConfig{}})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	go server.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Addr() == nil || server.AuthToken() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("Server did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatalf("Stat(token file) error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Token file mode = %v, want 0600", mode)
+	}
+	token, err := ReadAuthToken(tokenPath)
+	if err != nil || token != server.AuthToken() || len(token) != 64 {
+		t.Errorf("ReadAuthToken() = %q, %v, want the server's token", token, err)
+	}
+
+	if err := server.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := os.Stat(tokenPath); !os.IsNotExist(err) {
+		t.Errorf("Token file after Stop: %v, want it removed", err)
+	}
+}
+
+func TestAuthTokenPath_NoFile(t *testing.T) {
+	for _, address := range []string{"tcp://127.0.0.1:7000", "unix://@abstract"} {
+		if got := AuthTokenPath(address); got != "" {
+			t.Errorf("AuthTokenPath(%q) = %q, want none", address, got)
+		}
+	}
+}
+
+func TestPrincipal_HasRole(t *testing.T) {
+	p := &Principal{Name: "ci", Roles: []string{"read-only"}}
+	if !p.HasRole("read-only") || p.HasRole("admin") {
+		t.Errorf("HasRole() on %+v is wrong", p)
+	}
+	var none *Principal
+	if none.HasRole("read-only") {
+		t.Error("HasRole() on a nil principal = true")
+	}
+}
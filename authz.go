@@ -0,0 +1,175 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// AuthorizationPolicy decides whether a connection may call a method.
+// reason explains the decision in the log.
+//
+// conn is nil when the middleware runs outside a server connection.
+type AuthorizationPolicy interface {
+	Authorize(conn *Connection, method string) (allow bool, reason string)
+}
+
+// AuthorizationFunc adapts a function to an AuthorizationPolicy.
+//
+// Example:
+//
+//	policy := jsonrpc.AuthorizationFunc(func(conn *jsonrpc.Connection, method string) (bool, string) {
+//	    if strings.HasPrefix(method, "fs.") && conn.Principal().HasRole("read-only") {
+//	        return method == "fs.read", "read-only token"
+//	    }
+//	    return true, "default"
+//	})
+type AuthorizationFunc func(conn *Connection, method string) (allow bool, reason string)
+
+// Authorize calls f(conn, method).
+func (f AuthorizationFunc) Authorize(conn *Connection, method string) (bool, string) {
+	return f(conn, method)
+}
+
+// AuthorizationRule allows or denies methods to a set of connections.
+//
+// A connection matches the rule if it matches any of Principals, Roles, UIDs
+// and Owner, or if all of them are empty.
+type AuthorizationRule struct {
+	// Name identifies the rule in the log. If empty, its index is used.
+	Name string
+
+	// Methods are the method names or patterns the rule covers ("*" within
+	// a dot-separated segment, "**" across segments). A rule without
+	// methods covers none.
+	Methods []string
+
+	// Deny makes the rule refuse the methods instead of allowing them.
+	Deny bool
+
+	// Principals are names of authenticated principals (see AuthConfig).
+	Principals []string
+
+	// Roles are principal roles; a principal with any of them matches.
+	Roles []string
+
+	// UIDs are peer user IDs (see Connection.PeerCredentials).
+	UIDs []uint32
+
+	// Owner matches the user running the server: clients with its peer UID
+	// or authenticated with the generated token (OwnerPrincipal).
+	Owner bool
+}
+
+// AuthorizationRules is a policy made of rules. The first rule that covers
+// the method and matches the connection decides; requests no rule matches
+// are denied.
+//
+// Example:
+//
+//	jsonrpc.AuthorizationRules{
+//	    {Name: "admin", Methods: []string{"admin.*"}, UIDs: []uint32{0}, Owner: true},
+//	    {Name: "no-admin", Methods: []string{"admin.*"}, Deny: true},
+//	    {Name: "read-only", Methods: []string{"fs.write"}, Roles: []string{"read-only"}, Deny: true},
+//	    {Name: "default", Methods: []string{"**"}},
+//	}
+type AuthorizationRules []AuthorizationRule
+
+// Authorize implements AuthorizationPolicy.
+func (rules AuthorizationRules) Authorize(conn *Connection, method string) (bool, string) {
+	for i, rule := range rules {
+		if !matchAny(rule.Methods, method) || !rule.matches(conn) {
+			continue
+		}
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+		return !rule.Deny, name
+	}
+	return false, "no matching rule"
+}
+
+// matches reports whether conn is one of the connections the rule applies to.
+func (rule *AuthorizationRule) matches(conn *Connection) bool {
+	if len(rule.Principals) == 0 && len(rule.Roles) == 0 && len(rule.UIDs) == 0 && !rule.Owner {
+		return true
+	}
+	if conn == nil {
+		return false
+	}
+
+	if principal := conn.Principal(); principal != nil {
+		if contains(rule.Principals, principal.Name) {
+			return true
+		}
+		for _, role := range rule.Roles {
+			if principal.HasRole(role) {
+				return true
+			}
+		}
+		if rule.Owner && principal.Name == OwnerPrincipal {
+			return true
+		}
+	}
+
+	if peer, ok := conn.PeerCredentials(); ok {
+		for _, uid := range rule.UIDs {
+			if peer.UID == uid {
+				return true
+			}
+		}
+		if rule.Owner && os.Getuid() >= 0 && peer.UID == uint32(os.Getuid()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AuthorizationMiddleware creates middleware that refuses requests the policy
+// denies with Unauthorized, based on the connection's identity: its peer
+// credentials and the principal it authenticated as. Each decision is logged
+// to the request logger (see LoggerFromContext), denials at Warn level.
+//
+// AuthenticateMethod is not subject to the policy while ServerConfig.Auth is
+// set, since the client has no identity before calling it.
+//
+// Example:
+//
+//	server.RegisterMiddleware(jsonrpc.AuthorizationMiddleware(jsonrpc.AuthorizationRules{
+//	    {Methods: []string{"admin.*"}, Owner: true},
+//	    {Methods: []string{"admin.*"}, Deny: true},
+//	    {Methods: []string{"**"}},
+//	}))
+func AuthorizationMiddleware(policy AuthorizationPolicy) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			method := MethodFromContext(ctx)
+			conn := ConnectionFromContext(ctx)
+			if conn != nil && conn.authRequired() && method == AuthenticateMethod {
+				return next.Handle(ctx, params)
+			}
+
+			allow, reason := policy.Authorize(conn, method)
+
+			log := LoggerFromContext(ctx)
+			if conn != nil {
+				if principal := conn.Principal(); principal != nil {
+					log = log.With("principal", principal.Name)
+				}
+				if peer, ok := conn.PeerCredentials(); ok {
+					log = log.With("uid", peer.UID)
+				}
+			}
+			if !allow {
+				log.WarnContext(ctx, "request denied", "reason", reason)
+				return nil, NewUnauthorizedError(map[string]string{"method": method})
+			}
+			log.InfoContext(ctx, "request authorized", "reason", reason)
+
+			return next.Handle(ctx, params)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// newAuthzTestConnection returns a connection with the given identity.
+func newAuthzTestConnection(principal *Principal, peer *PeerCredentials) *Connection {
+	conn := &Connection{peer: peer}
+	if principal != nil {
+		conn.principal.Store(principal)
+	}
+	return conn
+}
+
+func TestAuthorizationRules(t *testing.T) {
+	rules := AuthorizationRules{
+		{Name: "admin", Methods: []string{"admin.*"}, UIDs: []uint32{0}, Owner: true},
+		{Name: "no-admin", Methods: []string{"admin.*"}, Deny: true},
+		{Name: "read-only", Methods: []string{"fs.write"}, Roles: []string{"read-only"}, Deny: true},
+		{Methods: []string{"fs.**", "status"}},
+	}
+
+	root := newAuthzTestConnection(nil, &PeerCredentials{UID: 0})
+	other := newAuthzTestConnection(nil, &PeerCredentials{UID: 4242})
+	owner := newAuthzTestConnection(&Principal{Name: OwnerPrincipal}, nil)
+	ci := newAuthzTestConnection(&Principal{Name: "ci", Roles: []string{"read-only"}}, nil)
+
+	tests := []struct {
+		name       string
+		conn       *Connection
+		method     string
+		wantAllow  bool
+		wantReason string
+	}{
+		{"root admin", root, "admin.reset", true, "admin"},
+		{"owner token admin", owner, "admin.reset", true, "admin"},
+		{"other admin", other, "admin.reset", false, "no-admin"},
+		{"read-only write", ci, "fs.write", false, "read-only"},
+		{"read-only read", ci, "fs.read", true, "rule 3"},
+		{"other write", other, "fs.write", true, "rule 3"},
+		{"no connection", nil, "status", true, "rule 3"},
+		{"no rule", other, "debug.dump", false, "no matching rule"},
+	}
+	for _, tt := range tests {
+		allow, reason := rules.Authorize(tt.conn, tt.method)
+		if allow != tt.wantAllow || reason != tt.wantReason {
+			t.Errorf("%s: Authorize(%q) = %v, %q, want %v, %q", tt.name, tt.method, allow, reason, tt.wantAllow, tt.wantReason)
+		}
+	}
+}
+
+func TestAuthorizationRules_OwnerUID(t *testing.T) {
+	if os.Getuid() < 0 {
+		t.Skip("No user IDs on this platform")
+	}
+
+	rules := AuthorizationRules{{Methods: []string{"admin.*"}, Owner: true}}
+	self := newAuthzTestConnection(nil, &PeerCredentials{UID: uint32(os.Getuid())})
+	if allow, _ := rules.Authorize(self, "admin.reset"); !allow {
+		t.Error("Authorize() for the server's own user = false, want true")
+	}
+	stranger := newAuthzTestConnection(nil, &PeerCredentials{UID: uint32(os.Getuid()) + 1})
+	if allow, _ := rules.Authorize(stranger, "admin.reset"); allow {
+		t.Error("Authorize() for another user = true, want false")
+	}
+}
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	logger, buf := newBufferLogger()
+	policy := AuthorizationFunc(func(conn *Connection, method string) (bool, string) {
+		if conn.Principal().HasRole("read-only") && method == "fs.write" {
+			return false, "read-only token"
+		}
+		return true, "default"
+	})
+
+	handler := Chain(HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "ok", nil
+	}), AuthorizationMiddleware(policy))
+
+	conn := newAuthzTestConnection(&Principal{Name: "ci", Roles: []string{"read-only"}}, &PeerCredentials{UID: 1000})
+	ctx := WithLogger(WithConnection(context.Background(), conn), logger)
+
+	_, err := handler.Handle(WithMethod(ctx, "fs.write"), nil)
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.Code != Unauthorized {
+		t.Fatalf("Handle(fs.write) error = %v, want Unauthorized", err)
+	}
+	record := buf.find(t, "request denied")
+	if record == nil {
+		t.Fatal("No \"request denied\" record logged")
+	}
+	if record["level"] != "WARN" || record["reason"] != "read-only token" || record["principal"] != "ci" || record["uid"] != float64(1000) {
+		t.Errorf("Denial record = %v", record)
+	}
+
+	if result, err := handler.Handle(WithMethod(ctx, "fs.read"), nil); err != nil || result != "ok" {
+		t.Fatalf("Handle(fs.read) = %v, %v", result, err)
+	}
+	if record := buf.find(t, "request authorized"); record == nil || record["reason"] != "default" {
+		t.Errorf("Authorization record = %v", record)
+	}
+}
+
+func TestAuthorizationMiddleware_Authenticate(t *testing.T) {
+	server, err := NewServer(ServerConfig{
+		SocketPath: "tcp://127.0.0.1:0",
+		Auth:       &AuthConfig{Tokens: map[string]Principal{"ci-token": {Name: "ci", Roles: []string{"read-only"}}}},
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.RegisterMiddleware(AuthorizationMiddleware(AuthorizationRules{
+		{Methods: []string{"fs.write"}, Roles: []string{"read-only"}, Deny: true},
+		{Methods: []string{"fs.*"}},
+	}))
+	server.RegisterFunc("fs.write", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "written", nil
+	})
+
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+	conn := newConnection(conn1, server.registry, server.middleware, server)
+
+	// The rules do not cover authenticate, but it is always available
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"authenticate","params":{"token":"ci-token"},"id":1}`); errResp != nil {
+		t.Fatalf("authenticate error = %+v", errResp.Error)
+	}
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"fs.write","id":2}`); errResp == nil || errResp.Error.Code != Unauthorized {
+		t.Errorf("fs.write with a read-only token = %+v, want Unauthorized", errResp)
+	}
+}
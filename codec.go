@@ -8,6 +8,24 @@ import (
 	"sync"
 )
 
+// Codec reads and writes framed JSON-RPC messages on a transport.
+//
+// LineDelimitedCodec is used for socket transports. Other transports (HTTP
+// server-sent events, WebSocket) provide their own framing so the same
+// Connection logic can serve them.
+type Codec interface {
+	// ReadMessage returns the next raw JSON message.
+	ReadMessage() ([]byte, error)
+	// WriteMessage writes a single raw JSON message.
+	WriteMessage(data []byte) error
+	// ReadJSON reads and unmarshals the next message into v.
+	ReadJSON(v interface{}) error
+	// WriteJSON marshals v and writes it as a single message.
+	WriteJSON(v interface{}) error
+	// Close closes the underlying transport.
+	Close() error
+}
+
 // LineDelimitedCodec handles encoding and decoding of line-delimited JSON messages.
 // Each JSON message is terminated with a newline character ('\n').
 //
@@ -21,6 +39,9 @@ type LineDelimitedCodec struct {
 	writer *bufio.Writer
 	conn   io.ReadWriteCloser
 
+	// fds collects file descriptors received over a Unix socket (nil otherwise)
+	fds *fdReader
+
 	// Separate mutexes for reading and writing to allow concurrent operations
 	readMu  sync.Mutex
 	writeMu sync.Mutex
@@ -31,11 +52,21 @@ type LineDelimitedCodec struct {
 // The codec uses buffered I/O for efficient reading and writing of line-delimited
 // JSON messages.
 func NewCodec(conn io.ReadWriteCloser) *LineDelimitedCodec {
-	return &LineDelimitedCodec{
-		reader: bufio.NewReader(conn),
+	c := &LineDelimitedCodec{
 		writer: bufio.NewWriter(conn),
 		conn:   conn,
 	}
+
+	// Unix sockets are read through an fdReader so that file descriptors
+	// sent alongside messages are received (see ReadMessageWithFiles)
+	if fds := newFDReader(conn); fds != nil {
+		c.fds = fds
+		c.reader = bufio.NewReader(fds)
+	} else {
+		c.reader = bufio.NewReader(conn)
+	}
+
+	return c
 }
 
 // ReadMessage reads a single line-delimited JSON message from the connection.
@@ -52,6 +83,11 @@ func (c *LineDelimitedCodec) ReadMessage() ([]byte, error) {
 	c.readMu.Lock()
 	defer c.readMu.Unlock()
 
+	return c.readMessage()
+}
+
+// readMessage reads the next message. The caller must hold readMu.
+func (c *LineDelimitedCodec) readMessage() ([]byte, error) {
 	for {
 		// Read until newline
 		line, err := c.reader.ReadBytes('\n')
@@ -148,7 +184,9 @@ func (c *LineDelimitedCodec) WriteJSON(v interface{}) error {
 	return c.WriteMessage(data)
 }
 
-// Close closes the underlying connection.
+// Close closes the underlying connection and any received files that no
+// message claimed.
 func (c *LineDelimitedCodec) Close() error {
+	c.discardFiles()
 	return c.conn.Close()
 }
@@ -1,12 +1,19 @@
 package jsonrpcipc
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Connection represents a single client connection to the JSON-RPC server.
@@ -14,8 +21,8 @@ import (
 // Each connection has its own goroutine that reads requests from the client,
 // dispatches them to handlers, and sends back responses.
 type Connection struct {
-	conn     net.Conn
-	codec    *LineDelimitedCodec
+	conn     net.Conn // nil for transports without a socket (HTTP)
+	codec    Codec
 	registry *HandlerRegistry
 	notifier *NotificationManager
 
@@ -26,7 +33,39 @@ type Connection struct {
 	middleware []Middleware
 
 	// Connection metadata
-	remoteAddr string
+	id          uint64
+	remoteAddr  string
+	connectedAt time.Time
+	logger      *slog.Logger // Server logger with the conn_id attribute
+	peer        *PeerCredentials
+	stats       *connectionStats
+
+	// Handshake outcome (see HandshakeConfig), nil until it completes
+	handshake atomic.Pointer[handshakeState]
+
+	// Lifecycle state (see ConnectionState)
+	state atomic.Int32
+
+	// Authenticated identity (see AuthConfig), nil until the client
+	// authenticates, and the timer closing the connection if it never does
+	principal atomic.Pointer[Principal]
+	authTimer *time.Timer
+
+	// Application values (see Set)
+	valuesMu sync.RWMutex
+	values   map[string]interface{}
+
+	// Outbound messages, written in order by writeLoop
+	queue      *writeQueue
+	writerDone chan struct{}
+
+	// Topic subscriptions (see Subscribe)
+	subsMu        sync.Mutex
+	subscriptions map[string]struct{}
+
+	// Groups (see Join)
+	groupsMu sync.Mutex
+	groups   map[string]struct{}
 
 	// Lifecycle
 	closeOnce sync.Once
@@ -36,31 +75,67 @@ type Connection struct {
 	server *Server
 }
 
+// flushTimeout bounds how long a connection whose client went away keeps
+// writing the messages queued for it.
+const flushTimeout = time.Second
+
+// connectionIDs numbers connections for log correlation.
+var connectionIDs atomic.Uint64
+
 // newConnection creates a new connection.
 // This is an internal function called by the Server.
 func newConnection(conn net.Conn, registry *HandlerRegistry, middleware []Middleware, server *Server) *Connection {
-	ctx, cancel := context.WithCancel(context.Background())
+	c := newCodecConnection(context.Background(), NewCodec(conn), conn.RemoteAddr().String(), registry, middleware, server)
+	c.conn = conn
+	if cred, ok := peerCredentials(conn); ok {
+		c.peer = &cred
+	}
+	return c
+}
 
-	codec := NewCodec(conn)
+// newCodecConnection creates a connection that exchanges messages through codec.
+// It is used directly by transports that are not backed by a net.Conn.
+// The connection's context, seen by its handlers, is derived from parent.
+func newCodecConnection(parent context.Context, codec Codec, remoteAddr string, registry *HandlerRegistry, middleware []Middleware, server *Server) *Connection {
+	ctx, cancel := context.WithCancel(parent)
+
+	id := connectionIDs.Add(1)
+	logger := slog.Default()
+	stats := &connectionStats{}
+	metered := &meteredCodec{Codec: codec, stats: stats}
+	queue := newWriteQueue(0, QueueBlock)
+	if server != nil {
+		logger = server.config.SlogLogger
+		metered.metrics = server.metrics
+		queue = newWriteQueue(server.config.WriteQueueSize, server.config.WriteQueuePolicy)
+		queue.onDrop = server.metrics.dropped
+	}
 
-	return &Connection{
-		conn:       conn,
-		codec:      codec,
-		registry:   registry,
-		notifier:   NewNotificationManager(codec),
-		ctx:        ctx,
-		cancel:     cancel,
-		middleware: middleware,
-		remoteAddr: conn.RemoteAddr().String(),
-		closed:     make(chan struct{}),
-		server:     server,
+	c := &Connection{
+		codec:       metered,
+		registry:    registry,
+		notifier:    NewNotificationManager(metered),
+		ctx:         ctx,
+		cancel:      cancel,
+		middleware:  middleware,
+		id:          id,
+		remoteAddr:  remoteAddr,
+		connectedAt: time.Now(),
+		stats:       stats,
+		logger:      logger.With("conn_id", id),
+		queue:       queue,
+		writerDone:  make(chan struct{}),
+		closed:      make(chan struct{}),
+		server:      server,
 	}
+	go c.writeLoop()
+	return c
 }
 
 // Serve starts serving requests on this connection.
 // This method blocks until the connection is closed.
 func (c *Connection) Serve() {
-	defer c.Close()
+	defer c.closeAfterFlush()
 
 	for {
 		select {
@@ -85,42 +160,292 @@ func (c *Connection) Serve() {
 // handleNext reads and handles the next message from the client.
 func (c *Connection) handleNext() error {
 	// Read raw message
+	data, err := c.codec.ReadMessage()
+	if err != nil {
+		// The transport failed (EOF, reset, closed) - nothing more can be read
+		if !errors.Is(err, io.EOF) && !c.IsClosed() {
+			c.logger.Debug("connection read failed", "error", err)
+		}
+		return io.EOF
+	}
+
+	reply, err := c.handleMessage(data)
+	if err != nil {
+		c.logger.Warn("invalid message", "error", err)
+	}
+	if reply != nil {
+		if werr := c.writeReply(reply); werr != nil {
+			c.logger.Debug("failed to send reply", "error", werr)
+		}
+	}
+	if c.exited() {
+		// A handler ended the connection (see StateExited)
+		return io.EOF
+	}
+	return err
+}
+
+// writeReply queues a reply built by handleMessage or dispatch.
+// Replies are never dropped by the write queue's overflow policy; instead
+// writeReply waits while the queue is full of replies, which stops the read
+// loop for a client that does not read them.
+func (c *Connection) writeReply(reply interface{}) error {
+	m := &outbound{reply: true}
+	if r, ok := reply.(*fileReply); ok {
+		reply, m.files, m.ownsFiles = r.response, r.files, true
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		m.finish(nil)
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	m.data = data
+	return c.enqueue(m)
+}
+
+// notify queues a notification, subject to the write queue's overflow policy.
+func (c *Connection) notify(notification *Notification) error {
+	if c.queue == nil {
+		// Connection built without newCodecConnection
+		return c.notifier.send(notification)
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.enqueue(&outbound{data: data, droppable: true})
+}
+
+// enqueue adds m to the write queue. With QueueDisconnect, a full queue
+// closes the connection.
+func (c *Connection) enqueue(m *outbound) error {
+	err := c.queue.push(m)
+	if err == nil {
+		return nil
+	}
+
+	m.finish(err)
+	if errors.Is(err, ErrWriteQueueFull) && c.queue.policy == QueueDisconnect {
+		c.logger.Warn("write queue full, disconnecting client", "queue_size", c.queue.size)
+		c.Close()
+	}
+	return err
+}
+
+// writeLoop writes queued messages until the queue is closed or drained.
+func (c *Connection) writeLoop() {
+	defer close(c.writerDone)
+
+	for {
+		m := c.queue.pop()
+		if m == nil {
+			return
+		}
+
+		var err error
+		if len(m.files) > 0 {
+			err = c.codec.(fileCodec).writeMessageWithFiles(m.data, m.files)
+		} else {
+			err = c.codec.WriteMessage(m.data)
+		}
+		if err != nil && m.done == nil && !c.IsClosed() {
+			c.logger.Debug("failed to send message", "error", err)
+		}
+		m.finish(err)
+	}
+}
+
+// closeAfterFlush closes the connection once the messages already queued
+// have been written, or after flushTimeout if the client is not reading.
+func (c *Connection) closeAfterFlush() {
+	c.queue.drain()
+	select {
+	case <-c.writerDone:
+	case <-time.After(flushTimeout):
+	}
+	c.Close()
+}
+
+// handleMessage processes a raw message, which may be a single JSON-RPC
+// message or a batch (JSON array).
+//
+// It returns the reply to send back (a response, or a slice of responses for
+// a batch), or nil if nothing should be sent (notifications only).
+// The returned error reports malformed input; a reply may still be present.
+func (c *Connection) handleMessage(data []byte) (interface{}, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return c.handleBatch(trimmed)
+	}
+
 	var msg Message
-	if err := c.codec.ReadJSON(&msg); err != nil {
-		if err == io.EOF {
-			return err
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.discardFiles()
+		return c.errorResponse(nil, NewParseError(err.Error())), fmt.Errorf("failed to read message: %w", err)
+	}
+
+	return c.handleSingle(&msg)
+}
+
+// handleBatch processes a JSON-RPC batch.
+// Requests are processed in order and their responses collected into one array.
+func (c *Connection) handleBatch(data []byte) (interface{}, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		c.discardFiles()
+		return c.errorResponse(nil, NewParseError(err.Error())), fmt.Errorf("failed to read batch: %w", err)
+	}
+
+	if len(items) == 0 {
+		return c.errorResponse(nil, NewInvalidRequestError("empty batch")), fmt.Errorf("empty batch")
+	}
+
+	replies := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		var msg Message
+		if err := json.Unmarshal(item, &msg); err != nil {
+			if files, err := c.takeFiles(countFDs(item)); err == nil {
+				closeFiles(files)
+			}
+			replies = append(replies, c.errorResponse(nil, NewInvalidRequestError(err.Error())))
+			continue
+		}
+		reply, _ := c.handleSingle(&msg)
+		if r, ok := reply.(*fileReply); ok {
+			// Files belong to a whole message, so a batch cannot carry them per response
+			closeFiles(r.files)
+			reply = c.errorResponse(r.response.ID, NewInternalError("file results are not supported in batches"))
+		}
+		if reply != nil {
+			replies = append(replies, reply)
 		}
-		// Send parse error
-		c.sendError(nil, NewParseError(err.Error()))
-		return fmt.Errorf("failed to read message: %w", err)
+	}
+
+	// A batch of notifications gets no reply at all
+	if len(replies) == 0 {
+		return nil, nil
+	}
+	return replies, nil
+}
+
+// handleSingle processes one decoded message and returns its reply, if any.
+func (c *Connection) handleSingle(msg *Message) (interface{}, error) {
+	// Claim attached files first, so the next message gets its own
+	files, err := c.takeFiles(msg.FDs)
+	if err != nil {
+		return c.errorResponse(msg.ID, NewInvalidRequestError(err.Error())), err
 	}
 
 	// Handle based on message type
 	if msg.IsRequest() {
 		req, err := msg.ToRequest()
 		if err != nil {
-			c.sendError(msg.ID, NewInvalidRequestError(err.Error()))
-			return err
+			closeFiles(files)
+			return c.errorResponse(msg.ID, NewInvalidRequestError(err.Error())), err
 		}
-		c.handleRequest(req)
+		if req.Meta != nil && c.strict() {
+			closeFiles(files)
+			return c.errorResponse(msg.ID, NewInvalidRequestError("meta member is not allowed")), nil
+		}
+		return c.dispatch(req, files), nil
 	} else if msg.IsNotification() {
 		// Server can receive notifications from clients (though uncommon)
 		// For now, we just ignore them
-	} else {
-		// Invalid message (not a request or notification)
-		c.sendError(msg.ID, NewInvalidRequestError("message must have method field"))
+		closeFiles(files)
+		return nil, nil
 	}
 
-	return nil
+	// Invalid message (not a request or notification)
+	closeFiles(files)
+	return c.errorResponse(msg.ID, NewInvalidRequestError("message must have method field")), nil
 }
 
-// handleRequest processes a JSON-RPC request.
+// takeFiles claims the next n files received on the connection.
+func (c *Connection) takeFiles(n int) ([]*os.File, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	codec, ok := filesSupported(c.codec)
+	if !ok {
+		return nil, ErrFilePassingUnsupported
+	}
+	return codec.takeFiles(n)
+}
+
+// discardFiles closes the files received on the connection that no message
+// has claimed. A line that cannot be parsed does not say how many files came
+// with it, so none of the queued files can be attributed reliably.
+func (c *Connection) discardFiles() {
+	if codec, ok := filesSupported(c.codec); ok {
+		codec.discardFiles()
+	}
+}
+
+// handleRequest processes a JSON-RPC request and writes the response.
 func (c *Connection) handleRequest(req *Request) {
-	// Look up handler
+	c.writeReply(c.dispatch(req, nil))
+}
+
+// dispatch runs a request through the middleware chain and its handler,
+// returning the *Response, *ErrorResponse or *fileReply to send back.
+// files are the files attached to the request; the handler owns them.
+func (c *Connection) dispatch(req *Request, files []*os.File) interface{} {
+	metrics := c.metrics()
+	metrics.requestStarted()
+	c.stats.inFlight.Add(1)
+	defer c.stats.inFlight.Add(-1)
+	start := time.Now()
+
+	// Look up handler, falling back to the server's built-in methods
 	handler, ok := c.registry.Get(req.Method)
+	authenticating := c.authRequired() && req.Method == AuthenticateMethod
+	if c.handshakeRequired() && req.Method == InitializeMethod {
+		// The handshake cannot be replaced by a registered handler
+		handler, ok = c.server.builtins[InitializeMethod]
+	} else if authenticating {
+		// Nor can authentication
+		handler, ok = c.server.builtins[AuthenticateMethod]
+	} else if !ok && c.server != nil {
+		handler, ok = c.server.builtins[req.Method]
+	}
+
+	// Until the client authenticates, only authentication is served. This
+	// comes first so unauthenticated clients cannot probe for methods.
+	if rpcErr := c.checkAuth(req); rpcErr != nil {
+		closeFiles(files)
+		label := req.Method
+		if !ok {
+			label = unknownMethodLabel
+		}
+		metrics.requestFinished(label, time.Since(start), rpcErr.Code)
+		return c.errorResponse(req.ID, rpcErr)
+	}
+
 	if !ok {
-		c.sendError(req.ID, NewMethodNotFoundError(req.Method))
-		return
+		closeFiles(files)
+		metrics.requestFinished(unknownMethodLabel, time.Since(start), MethodNotFound)
+		return c.errorResponse(req.ID, NewMethodNotFoundError(req.Method))
+	}
+
+	// Refuse methods that are not available in the connection's state
+	// (authentication is available in all of them)
+	if lc := c.lifecycle(); lc != nil && !authenticating {
+		if state := c.State(); !lc.allows(state, req.Method) {
+			closeFiles(files)
+			rpcErr := lc.refusedError(state, req.Method)
+			metrics.requestFinished(req.Method, time.Since(start), rpcErr.Code)
+			return c.errorResponse(req.ID, rpcErr)
+		}
+	}
+
+	// Until the handshake completes, only the handshake is served
+	// (authentication may come before it)
+	if c.handshakeRequired() && req.Method != InitializeMethod && !c.Initialized() && !authenticating {
+		closeFiles(files)
+		metrics.requestFinished(req.Method, time.Since(start), ServerNotInitialized)
+		return c.errorResponse(req.ID, NewServerNotInitializedError(map[string]string{"method": req.Method}))
 	}
 
 	// Apply middleware
@@ -133,45 +458,144 @@ func (c *Connection) handleRequest(req *Request) {
 	ctx = WithMethod(ctx, req.Method)
 	ctx = WithRequestID(ctx, req.ID)
 	ctx = WithConnection(ctx, c)
+	logger := c.logger.With("method", req.Method, "request_id", req.ID)
+	if tc, ok := traceContextFromMeta(req.Meta); ok {
+		ctx = WithTraceContext(ctx, tc)
+		logger = logger.With("traceparent", tc.TraceParent)
+	}
+	ctx = WithLogger(ctx, logger)
+	if req.Meta != nil {
+		ctx = WithMeta(ctx, req.Meta)
+	}
+	if len(files) > 0 {
+		ctx = WithFiles(ctx, files)
+	}
+	rm := &responseMeta{}
+	if !c.strict() {
+		ctx = context.WithValue(ctx, contextKeyResponseMeta, rm)
+	}
+
+	// Apply the client's deadline, if it set one
+	var maxTimeout time.Duration
+	if c.server != nil {
+		maxTimeout = c.server.config.MaxRequestTimeout
+	}
+	deadline, err := requestDeadline(req.Meta, start, maxTimeout)
+	if err != nil {
+		closeFiles(files)
+		metrics.requestFinished(req.Method, time.Since(start), InvalidRequest)
+		return c.errorResponse(req.ID, NewInvalidRequestError(err.Error()))
+	}
+	if !deadline.IsZero() {
+		if !time.Now().Before(deadline) {
+			closeFiles(files)
+			metrics.requestFinished(req.Method, time.Since(start), DeadlineExceeded)
+			return c.errorResponse(req.ID, NewDeadlineExceededError("deadline passed before the request was handled"))
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	var finish func(error)
+	if c.server != nil && c.server.config.Tracer != nil {
+		ctx, finish = c.server.config.Tracer.Start(ctx, req.Method)
+	}
 
 	// Execute handler
 	result, err := handler.Handle(ctx, req.Params)
+	if finish != nil {
+		finish(err)
+	}
+
+	// The client has given up on a late result
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		if fr, ok := result.(*FileResult); ok {
+			closeFiles(fr.Files)
+		}
+		result, err = nil, NewDeadlineExceededError(map[string]interface{}{
+			"elapsed": time.Since(start).String(),
+		})
+	}
 
-	// Send response
 	if err != nil {
 		rpcErr := ToRPCError(err)
-		c.sendError(req.ID, rpcErr)
-	} else {
-		c.sendResult(req.ID, result)
+		metrics.requestFinished(req.Method, time.Since(start), rpcErr.Code)
+		return withReplyMeta(c.errorResponse(req.ID, rpcErr), rm.get())
+	}
+	metrics.requestFinished(req.Method, time.Since(start), 0)
+	if fr, ok := result.(*FileResult); ok {
+		return withReplyMeta(c.fileResponse(req.ID, fr), rm.get())
 	}
+	return withReplyMeta(c.resultResponse(req.ID, result), rm.get())
 }
 
-// sendResult sends a success response to the client.
-func (c *Connection) sendResult(id interface{}, result interface{}) error {
-	response := &Response{
+// strict reports whether the server only speaks plain JSON-RPC 2.0
+// (see ServerConfig.StrictSpec).
+func (c *Connection) strict() bool {
+	return c.server != nil && c.server.config.StrictSpec
+}
+
+// metrics returns the server's metrics, or nil for a connection without a server.
+func (c *Connection) metrics() *Metrics {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.metrics
+}
+
+// fileResponse builds a success response that carries files.
+func (c *Connection) fileResponse(id interface{}, fr *FileResult) interface{} {
+	if len(fr.Files) == 0 {
+		return c.resultResponse(id, fr.Result)
+	}
+	if _, ok := filesSupported(c.codec); !ok {
+		closeFiles(fr.Files)
+		return c.errorResponse(id, NewInternalError(ErrFilePassingUnsupported.Error()))
+	}
+
+	resp := c.resultResponse(id, fr.Result)
+	resp.FDs = len(fr.Files)
+	return &fileReply{response: resp, files: fr.Files}
+}
+
+// resultResponse builds a success response.
+func (c *Connection) resultResponse(id interface{}, result interface{}) *Response {
+	return &Response{
 		JSONRPC: "2.0",
 		Result:  result,
 		ID:      id,
 	}
-
-	return c.codec.WriteJSON(response)
 }
 
-// sendError sends an error response to the client.
-func (c *Connection) sendError(id interface{}, rpcErr *RPCError) error {
-	response := &ErrorResponse{
+// errorResponse builds an error response.
+func (c *Connection) errorResponse(id interface{}, rpcErr *RPCError) *ErrorResponse {
+	return &ErrorResponse{
 		JSONRPC: "2.0",
 		Error:   rpcErr,
 		ID:      id,
 	}
+}
 
-	return c.codec.WriteJSON(response)
+// sendResult sends a success response to the client.
+func (c *Connection) sendResult(id interface{}, result interface{}) error {
+	return c.writeReply(c.resultResponse(id, result))
+}
+
+// sendError sends an error response to the client.
+func (c *Connection) sendError(id interface{}, rpcErr *RPCError) error {
+	return c.writeReply(c.errorResponse(id, rpcErr))
 }
 
 // Notify sends a notification to the client.
 //
 // Notifications are one-way messages from server to client that don't expect a response.
 //
+// The notification is queued and written by the connection's writer
+// goroutine, so Notify returns before the client has read it. When the queue
+// is full, ServerConfig.WriteQueuePolicy decides whether Notify waits, or the
+// notification is dropped (see QueuePolicy).
+//
 // Example:
 //
 //	conn.Notify("progress", map[string]interface{}{
@@ -181,14 +605,170 @@ func (c *Connection) sendError(id interface{}, rpcErr *RPCError) error {
 //
 // Thread-safety: This method is safe to call concurrently.
 func (c *Connection) Notify(method string, params interface{}) error {
-	return c.notifier.Send(method, params)
+	return c.notify(&Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NotifyContext sends a notification carrying the trace context of ctx
+// (see TraceContextFromContext) in its "meta" member, so the client can
+// attribute it to the request being handled. Without a trace context it is
+// the same as Notify, as it is with ServerConfig.StrictSpec.
+//
+// Example:
+//
+//	func handler(ctx context.Context, params json.RawMessage) (interface{}, error) {
+//	    conn := jsonrpc.ConnectionFromContext(ctx)
+//	    conn.NotifyContext(ctx, "progress", map[string]int{"percentage": 50})
+//	    return "done", nil
+//	}
+//
+// Thread-safety: This method is safe to call concurrently.
+func (c *Connection) NotifyContext(ctx context.Context, method string, params interface{}) error {
+	var meta Meta
+	if !c.strict() {
+		meta = traceMeta(ctx)
+	}
+	return c.notify(&Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+		Meta:    meta,
+	})
+}
+
+// NotifyWithFDs sends a notification with files attached, for handing
+// large files or pipes to the client without copying them through JSON.
+// The params refer to the files by index (0 is the first file).
+//
+// The files are duplicated into the client; the caller keeps ownership and
+// may close them once NotifyWithFDs returns. NotifyWithFDs therefore waits
+// until the notification is written, and it is never dropped from the write
+// queue.
+//
+// Unix sockets only; other transports return ErrFilePassingUnsupported.
+//
+// Example:
+//
+//	r, w, _ := os.Pipe()
+//	conn.NotifyWithFDs("output.stream", map[string]int{"pipe": 0}, []*os.File{r})
+//	r.Close()
+//
+// Thread-safety: This method is safe to call concurrently.
+func (c *Connection) NotifyWithFDs(method string, params interface{}, files []*os.File) error {
+	if c.queue == nil {
+		return c.notifier.SendWithFiles(method, params, files)
+	}
+	if _, ok := filesSupported(c.codec); !ok {
+		return ErrFilePassingUnsupported
+	}
+
+	data, err := json.Marshal(&Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+		FDs:     len(files),
+	})
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+
+	done := make(chan error, 1)
+	if err := c.enqueue(&outbound{data: data, files: files, done: done}); err != nil {
+		return err
+	}
+	return <-done
+}
+
+// DroppedMessages returns the number of notifications to this client that
+// were dropped because its write queue was full (see QueuePolicy).
+func (c *Connection) DroppedMessages() uint64 {
+	return c.queue.dropped.Load()
+}
+
+// ID returns the connection's unique ID. IDs are assigned in increasing
+// order as connections are accepted and are never reused while the process
+// runs. The ID is also the "conn_id" attribute of the connection's log records.
+func (c *Connection) ID() uint64 {
+	return c.id
+}
+
+// ConnectedAt returns the time the connection was accepted.
+func (c *Connection) ConnectedAt() time.Time {
+	return c.connectedAt
+}
+
+// PeerCredentials returns the credentials of the client process.
+// The boolean is false unless the client connected over a Unix socket on Linux.
+func (c *Connection) PeerCredentials() (PeerCredentials, bool) {
+	if c.peer == nil {
+		return PeerCredentials{}, false
+	}
+	return *c.peer, true
 }
 
 // RemoteAddr returns the remote address of the client.
+// It is empty or the same for all Unix socket clients; use ID to tell
+// connections apart.
 func (c *Connection) RemoteAddr() string {
 	return c.remoteAddr
 }
 
+// Set stores a value on the connection, for session state shared between
+// handlers and middleware such as the authenticated user. Values live as
+// long as the connection.
+//
+// Example:
+//
+//	conn := jsonrpc.ConnectionFromContext(ctx)
+//	conn.Set("user", user)
+//
+// Thread-safety: This method is safe to call concurrently.
+func (c *Connection) Set(key string, value interface{}) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+// Get returns the value stored under key with Set, and whether there is one.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (c *Connection) Get(key string) (interface{}, bool) {
+	c.valuesMu.RLock()
+	defer c.valuesMu.RUnlock()
+
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// Delete removes the value stored under key.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (c *Connection) Delete(key string) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	delete(c.values, key)
+}
+
+// TLSConnectionState returns the TLS state of the connection.
+// The boolean is false if the client did not connect over "tls://".
+//
+// Use it to inspect the verified client certificate chain when
+// ServerConfig.TLSClientCAs is set.
+func (c *Connection) TLSConnectionState() (tls.ConnectionState, bool) {
+	tlsConn, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+	return tlsConn.ConnectionState(), true
+}
+
 // Close closes the connection and cancels all pending requests.
 //
 // This method is safe to call multiple times.
@@ -202,13 +782,20 @@ func (c *Connection) Close() error {
 		// Cancel context
 		c.cancel()
 
-		// Close notifier
+		// Close notifier and discard unsent messages
 		c.notifier.Close()
+		c.queue.close()
+
+		// Leave all groups
+		c.leaveAll()
 
-		// Close underlying connection
-		if c.conn != nil {
-			err = c.conn.Close()
+		if c.authTimer != nil {
+			c.authTimer.Stop()
 		}
+
+		// Close underlying connection (through the codec, so transports
+		// with a closing handshake can perform it)
+		err = c.codec.Close()
 	})
 
 	return err
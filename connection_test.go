@@ -3,8 +3,10 @@ package jsonrpcipc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -65,6 +67,76 @@ func TestConnection_RemoteAddr(t *testing.T) {
 	}
 }
 
+func TestConnection_ID(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+	conn3, conn4 := newMockConnPair()
+	defer conn3.Close()
+	defer conn4.Close()
+
+	before := time.Now()
+	first := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+	second := newConnection(conn3, NewHandlerRegistry(), nil, nil)
+
+	if first.ID() == 0 || second.ID() <= first.ID() {
+		t.Errorf("ID() = %d then %d, want increasing non-zero IDs", first.ID(), second.ID())
+	}
+	if at := first.ConnectedAt(); at.Before(before) || at.After(time.Now()) {
+		t.Errorf("ConnectedAt() = %v, want the time the connection was created", at)
+	}
+}
+
+func TestConnection_Values(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+
+	if _, ok := connection.Get("user"); ok {
+		t.Error("Get() on an empty connection reported a value")
+	}
+
+	connection.Set("user", "alice")
+	if v, ok := connection.Get("user"); !ok || v != "alice" {
+		t.Errorf("Get(user) = %v, %v, want alice, true", v, ok)
+	}
+
+	connection.Set("user", "bob")
+	if v, _ := connection.Get("user"); v != "bob" {
+		t.Errorf("Get(user) after overwrite = %v, want bob", v)
+	}
+
+	connection.Delete("user")
+	if _, ok := connection.Get("user"); ok {
+		t.Error("Get() reported a value after Delete")
+	}
+}
+
+func TestConnection_ValuesConcurrent(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i%2)
+			for j := 0; j < 100; j++ {
+				connection.Set(key, j)
+				connection.Get(key)
+				connection.Delete(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
 func TestConnection_Context(t *testing.T) {
 	conn1, conn2 := newMockConnPair()
 	defer conn1.Close()
@@ -883,3 +955,111 @@ func TestConnection_ContextPropagation(t *testing.T) {
 		t.Error("Connection from context does not match")
 	}
 }
+
+func TestConnection_HandleNext_Batch(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("echo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return string(params), nil
+	})
+
+	connection := newConnection(conn1, registry, nil, nil)
+
+	repliesCh := make(chan []Message, 1)
+	go func() {
+		conn2.Write([]byte(`[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},` +
+			`{"jsonrpc":"2.0","method":"echo","params":"b"},` +
+			`{"jsonrpc":"2.0","method":"echo","params":"c","id":2}]` + "\n"))
+
+		var replies []Message
+		if err := NewCodec(conn2).ReadJSON(&replies); err == nil {
+			repliesCh <- replies
+		}
+	}()
+
+	if err := connection.handleNext(); err != nil {
+		t.Fatalf("handleNext() error: %v", err)
+	}
+
+	select {
+	case replies := <-repliesCh:
+		if len(replies) != 2 {
+			t.Fatalf("Batch replies = %d, want 2 (notification has no reply)", len(replies))
+		}
+		if !compareIDs(replies[0].ID, 1) || !compareIDs(replies[1].ID, 2) {
+			t.Errorf("Reply IDs = %v, %v, want 1, 2", replies[0].ID, replies[1].ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive batch reply")
+	}
+}
+
+func TestConnection_HandleMessage_EmptyBatch(t *testing.T) {
+	connection := newCodecConnection(context.Background(), discardCodec{}, "test", NewHandlerRegistry(), nil, nil)
+
+	reply, err := connection.handleMessage([]byte(`[]`))
+	if err == nil {
+		t.Error("handleMessage() with empty batch should return error")
+	}
+
+	errResp, ok := reply.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Reply type = %T, want *ErrorResponse", reply)
+	}
+	if errResp.Error.Code != InvalidRequest {
+		t.Errorf("Error code = %d, want %d", errResp.Error.Code, InvalidRequest)
+	}
+}
+
+func TestConnection_HandleMessage_NotificationBatch(t *testing.T) {
+	registry := NewHandlerRegistry()
+	connection := newCodecConnection(context.Background(), discardCodec{}, "test", registry, nil, nil)
+
+	reply, err := connection.handleMessage([]byte(`[{"jsonrpc":"2.0","method":"a"},{"jsonrpc":"2.0","method":"b"}]`))
+	if err != nil {
+		t.Errorf("handleMessage() error: %v", err)
+	}
+	if reply != nil {
+		t.Errorf("Reply = %v, want nil for a batch of notifications", reply)
+	}
+}
+
+func TestConnection_RequestLogger(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	logger, buf := newBufferLogger()
+	server, err := NewServer(ServerConfig{SocketPath: "tcp://127.0.0.1:0", SlogLogger: logger})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("test.method", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		LoggerFromContext(ctx).Info("handling")
+		return "ok", nil
+	})
+
+	connection := newConnection(conn1, registry, nil, server)
+	go NewCodec(conn2).ReadMessage()
+
+	connection.handleRequest(&Request{JSONRPC: "2.0", Method: "test.method", ID: 3})
+
+	record := buf.find(t, "handling")
+	if record == nil {
+		t.Fatal("Handler log record not written to SlogLogger")
+	}
+	if record["method"] != "test.method" {
+		t.Errorf("method = %v, want test.method", record["method"])
+	}
+	if record["request_id"] != float64(3) {
+		t.Errorf("request_id = %v, want 3", record["request_id"])
+	}
+	if record["conn_id"] != float64(connection.id) {
+		t.Errorf("conn_id = %v, want %d", record["conn_id"], connection.id)
+	}
+}
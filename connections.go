@@ -0,0 +1,149 @@
+package jsonrpcipc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DisconnectMethod is the notification sent to a client before
+// Server.Disconnect closes its connection. Its params carry the reason:
+//
+//	← {"jsonrpc":"2.0","method":"$/disconnect","params":{"reason":"idle too long"}}
+const DisconnectMethod = "$/disconnect"
+
+// ErrConnectionNotFound is returned by Server.Disconnect for an ID that
+// matches no open connection.
+var ErrConnectionNotFound = errors.New("connection not found")
+
+// PeerCredentials identifies the process at the other end of a Unix socket.
+type PeerCredentials struct {
+	PID int
+	UID uint32
+	GID uint32
+}
+
+// ConnectionInfo is a snapshot of a connection's state, for admin tooling.
+type ConnectionInfo struct {
+	ID          uint64
+	RemoteAddr  string
+	ConnectedAt time.Time
+	State       ConnectionState
+
+	// Peer holds the client's process credentials on Linux Unix sockets,
+	// and is nil otherwise.
+	Peer *PeerCredentials
+
+	// Principal is the identity the client authenticated as (see
+	// AuthConfig), or nil.
+	Principal *Principal
+
+	InFlight        int64  // Requests being handled
+	BytesIn         uint64 // Bytes of messages received from the client
+	BytesOut        uint64 // Bytes of messages sent to the client
+	DroppedMessages uint64 // Notifications dropped by a full write queue
+	Groups          []string
+}
+
+// Info returns a snapshot of the connection's state.
+func (c *Connection) Info() ConnectionInfo {
+	var peer *PeerCredentials
+	if c.peer != nil {
+		p := *c.peer
+		peer = &p
+	}
+
+	return ConnectionInfo{
+		ID:              c.id,
+		RemoteAddr:      c.remoteAddr,
+		ConnectedAt:     c.connectedAt,
+		State:           c.State(),
+		Peer:            peer,
+		Principal:       c.Principal(),
+		InFlight:        c.stats.inFlight.Load(),
+		BytesIn:         c.stats.bytesIn.Load(),
+		BytesOut:        c.stats.bytesOut.Load(),
+		DroppedMessages: c.DroppedMessages(),
+		Groups:          c.Groups(),
+	}
+}
+
+// disconnectParams are the params of the DisconnectMethod notification.
+type disconnectParams struct {
+	Reason string `json:"reason"`
+}
+
+// Connections returns a snapshot of the open connections, ordered by ID.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) Connections() []ConnectionInfo {
+	var infos []ConnectionInfo
+
+	s.connections.Range(func(key, value interface{}) bool {
+		infos = append(infos, key.(*Connection).Info())
+		return true
+	})
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Connection returns the open connection with the given ID, or nil.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) Connection(id uint64) *Connection {
+	var found *Connection
+
+	s.connections.Range(func(key, value interface{}) bool {
+		if conn := key.(*Connection); conn.id == id {
+			found = conn
+			return false
+		}
+		return true
+	})
+
+	return found
+}
+
+// Disconnect closes the connection with the given ID, after sending it a
+// DisconnectMethod notification with the reason. Messages already queued
+// for the client are sent first; Disconnect waits up to a second for them.
+//
+// Example:
+//
+//	for _, info := range server.Connections() {
+//	    if info.InFlight == 0 && time.Since(info.ConnectedAt) > 24*time.Hour {
+//	        server.Disconnect(info.ID, "session expired")
+//	    }
+//	}
+//
+// Returns ErrConnectionNotFound if no open connection has the ID.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) Disconnect(id uint64, reason string) error {
+	conn := s.Connection(id)
+	if conn == nil {
+		return ErrConnectionNotFound
+	}
+
+	conn.logger.Info("disconnecting client", "reason", reason)
+	conn.sendFinal(&Notification{
+		JSONRPC: "2.0",
+		Method:  DisconnectMethod,
+		Params:  disconnectParams{Reason: reason},
+	})
+	conn.closeAfterFlush()
+	return nil
+}
+
+// sendFinal queues a notification that the write queue's overflow policy
+// does not drop.
+func (c *Connection) sendFinal(notification *Notification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.enqueue(&outbound{data: data})
+}
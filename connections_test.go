@@ -0,0 +1,133 @@
+package jsonrpcipc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForConnections polls until the server has n connections.
+func waitForConnections(t *testing.T, server *Server, n int) []ConnectionInfo {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		infos := server.Connections()
+		if len(infos) == n {
+			return infos
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Connections() = %d connections, want %d", len(infos), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_Connections(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	if infos := server.Connections(); len(infos) != 0 {
+		t.Fatalf("Connections() = %v, want none", infos)
+	}
+
+	first, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer first.Close()
+	second, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer second.Close()
+
+	if _, err := callAdd(t, first); err != nil {
+		t.Fatalf("callAdd() error = %v", err)
+	}
+
+	infos := waitForConnections(t, server, 2)
+	if infos[0].ID >= infos[1].ID {
+		t.Errorf("Connections() IDs %d, %d, want ascending", infos[0].ID, infos[1].ID)
+	}
+	if infos[0].ConnectedAt.IsZero() || infos[0].RemoteAddr == "" {
+		t.Errorf("Connections()[0] = %+v, want connect time and address", infos[0])
+	}
+	if infos[0].Peer != nil {
+		t.Errorf("Peer = %+v for a TCP client, want nil", infos[0].Peer)
+	}
+
+	// The first client made one call; the second none
+	var called, idle ConnectionInfo
+	for _, info := range infos {
+		if info.BytesIn > 0 {
+			called = info
+		} else {
+			idle = info
+		}
+	}
+	if called.BytesIn == 0 || called.BytesOut == 0 || called.InFlight != 0 {
+		t.Errorf("Caller info = %+v, want bytes both ways and nothing in flight", called)
+	}
+	if idle.BytesOut != 0 {
+		t.Errorf("Idle client BytesOut = %d, want 0", idle.BytesOut)
+	}
+
+	conn := server.Connection(called.ID)
+	if conn == nil || conn.ID() != called.ID {
+		t.Fatalf("Connection(%d) = %v, want the connection", called.ID, conn)
+	}
+	if server.Connection(0) != nil {
+		t.Error("Connection(0) should be nil")
+	}
+}
+
+func TestServer_Disconnect(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer client.Close()
+
+	if err := server.Disconnect(12345678, "nope"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("Disconnect() of an unknown ID error = %v, want ErrConnectionNotFound", err)
+	}
+
+	info := waitForConnections(t, server, 1)[0]
+
+	done := make(chan error, 1)
+	go func() { done <- server.Disconnect(info.ID, "maintenance") }()
+
+	codec := NewCodec(client)
+	var notif struct {
+		Method string           `json:"method"`
+		Params disconnectParams `json:"params"`
+	}
+	if err := codec.ReadJSON(&notif); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if notif.Method != DisconnectMethod || notif.Params.Reason != "maintenance" {
+		t.Errorf("Notification = %+v, want %s with the reason", notif, DisconnectMethod)
+	}
+	if _, err := codec.ReadMessage(); err == nil {
+		t.Error("Connection still open after Disconnect()")
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Disconnect() error = %v", err)
+	}
+	waitForConnections(t, server, 0)
+}
+
+func TestConnection_InfoCopiesPeer(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{}, nil)
+	connection.peer = &PeerCredentials{PID: 42, UID: 1000, GID: 1000}
+
+	info := connection.Info()
+	info.Peer.UID = 0
+
+	if got, _ := connection.PeerCredentials(); got.UID != 1000 {
+		t.Errorf("PeerCredentials().UID = %d after changing Info().Peer, want 1000", got.UID)
+	}
+}
@@ -0,0 +1,82 @@
+package jsonrpcipc
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Meta keys for client-specified deadlines. A request may carry either or
+// both; the earlier one applies.
+//
+//	{"jsonrpc":"2.0","method":"build","id":1,"meta":{"timeout":30000}}
+//	{"jsonrpc":"2.0","method":"build","id":1,"meta":{"deadline":"2025-11-02T15:04:05.000Z"}}
+//
+// The handler context is given that deadline (capped by
+// ServerConfig.MaxRequestTimeout), and the client receives a DeadlineExceeded
+// error if the handler does not finish in time.
+const (
+	// MetaDeadline is an absolute deadline as an RFC 3339 timestamp.
+	MetaDeadline = "deadline"
+
+	// MetaTimeout is a timeout in milliseconds, counted from when the server
+	// receives the request.
+	MetaTimeout = "timeout"
+)
+
+// requestDeadline returns the deadline a request's metadata asks for, capped
+// by max if max is positive. The zero time means the request has none.
+func requestDeadline(meta Meta, now time.Time, max time.Duration) (time.Time, error) {
+	var deadline time.Time
+
+	switch v := meta[MetaDeadline].(type) {
+	case nil:
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid meta deadline: %w", err)
+		}
+		deadline = t
+	default:
+		return time.Time{}, fmt.Errorf("invalid meta deadline: must be an RFC 3339 string")
+	}
+
+	if v, ok := meta[MetaTimeout]; ok && v != nil {
+		ms, ok := toMilliseconds(v)
+		if !ok || ms < 0 {
+			return time.Time{}, fmt.Errorf("invalid meta timeout: must be a non-negative number of milliseconds")
+		}
+		// A Duration overflows after about 292 years, which would turn a
+		// huge timeout into a negative one
+		timeout := time.Duration(math.MaxInt64)
+		if ms < float64(math.MaxInt64/int64(time.Millisecond)) {
+			timeout = time.Duration(ms * float64(time.Millisecond))
+		}
+		t := now.Add(timeout)
+		if deadline.IsZero() || t.Before(deadline) {
+			deadline = t
+		}
+	}
+
+	if !deadline.IsZero() && max > 0 {
+		if limit := now.Add(max); deadline.After(limit) {
+			deadline = limit
+		}
+	}
+	return deadline, nil
+}
+
+// toMilliseconds converts a decoded JSON number (or a Go integer set by a
+// Go client) to float64.
+func toMilliseconds(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
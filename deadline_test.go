@@ -0,0 +1,158 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	now := time.Date(2025, 11, 2, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		meta    Meta
+		max     time.Duration
+		want    time.Time
+		wantErr bool
+	}{
+		{"none", nil, 0, time.Time{}, false},
+		{"timeout", Meta{MetaTimeout: 1500.0}, 0, now.Add(1500 * time.Millisecond), false},
+		{"integer timeout", Meta{MetaTimeout: 2000}, 0, now.Add(2 * time.Second), false},
+		{"deadline", Meta{MetaDeadline: "2025-11-02T15:00:05Z"}, 0, now.Add(5 * time.Second), false},
+		{"earlier of both", Meta{MetaDeadline: "2025-11-02T15:00:05Z", MetaTimeout: 1000.0}, 0, now.Add(time.Second), false},
+		{"capped", Meta{MetaTimeout: 60000.0}, 10 * time.Second, now.Add(10 * time.Second), false},
+		{"cap ignored without deadline", nil, 10 * time.Second, time.Time{}, false},
+		{"huge timeout", Meta{MetaTimeout: 1e20}, 0, now.Add(math.MaxInt64), false},
+		{"huge timeout capped", Meta{MetaTimeout: 1e20}, 10 * time.Second, now.Add(10 * time.Second), false},
+		{"negative timeout", Meta{MetaTimeout: -1.0}, 0, time.Time{}, true},
+		{"string timeout", Meta{MetaTimeout: "1000"}, 0, time.Time{}, true},
+		{"bad deadline", Meta{MetaDeadline: "tomorrow"}, 0, time.Time{}, true},
+		{"numeric deadline", Meta{MetaDeadline: 12.0}, 0, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := requestDeadline(tt.meta, now, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requestDeadline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("requestDeadline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// deadlineMethods returns a function registering a "wait" handler that
+// blocks until its context is done, and a "deadline" handler that reports the
+// context deadline. Both set *called.
+func deadlineMethods(called *bool) func(server *Server) {
+	return func(server *Server) {
+		server.RegisterFunc("wait", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			*called = true
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		server.RegisterFunc("deadline", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			*called = true
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				return nil, nil
+			}
+			return time.Until(deadline).Seconds(), nil
+		})
+	}
+}
+
+func TestConnection_ClientDeadlineExceeded(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{}, deadlineMethods(new(bool)))
+
+	start := time.Now()
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"wait","id":1,"meta":{"timeout":50}}`))
+
+	errResp, ok := reply.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *ErrorResponse", reply)
+	}
+	if errResp.Error.Code != DeadlineExceeded {
+		t.Errorf("Error code = %d, want %d", errResp.Error.Code, DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Handler ran for %v, want about 50ms", elapsed)
+	}
+}
+
+func TestConnection_ClientDeadlineAlreadyPassed(t *testing.T) {
+	called := false
+	connection := newTestConnection(t, ServerConfig{}, deadlineMethods(&called))
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"wait","id":1,"meta":{"deadline":"2000-01-01T00:00:00Z"}}`))
+
+	errResp, ok := reply.(*ErrorResponse)
+	if !ok || errResp.Error.Code != DeadlineExceeded {
+		t.Fatalf("Reply = %#v, want DeadlineExceeded", reply)
+	}
+	if called {
+		t.Error("Handler ran although the deadline had passed")
+	}
+}
+
+func TestConnection_ClientDeadlineCapped(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{MaxRequestTimeout: time.Second}, deadlineMethods(new(bool)))
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"deadline","id":1,"meta":{"timeout":60000}}`))
+
+	resp, ok := reply.(*Response)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *Response", reply)
+	}
+	if remaining, _ := resp.Result.(float64); remaining <= 0 || remaining > 1 {
+		t.Errorf("Handler deadline in %vs, want at most the 1s cap", resp.Result)
+	}
+}
+
+func TestConnection_HugeClientTimeoutCapped(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{MaxRequestTimeout: time.Second}, deadlineMethods(new(bool)))
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"deadline","id":1,"meta":{"timeout":1e20}}`))
+
+	resp, ok := reply.(*Response)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *Response", reply)
+	}
+	if remaining, _ := resp.Result.(float64); remaining <= 0 || remaining > 1 {
+		t.Errorf("Handler deadline in %vs, want at most the 1s cap", resp.Result)
+	}
+}
+
+func TestConnection_NoClientDeadline(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{MaxRequestTimeout: time.Second}, deadlineMethods(new(bool)))
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"deadline","id":1}`))
+
+	resp, ok := reply.(*Response)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *Response", reply)
+	}
+	if resp.Result != nil {
+		t.Errorf("Handler context has a deadline (%vs) without a client deadline", resp.Result)
+	}
+}
+
+func TestConnection_InvalidClientDeadline(t *testing.T) {
+	called := false
+	connection := newTestConnection(t, ServerConfig{}, deadlineMethods(&called))
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"wait","id":1,"meta":{"timeout":"soon"}}`))
+
+	errResp, ok := reply.(*ErrorResponse)
+	if !ok || errResp.Error.Code != InvalidRequest {
+		t.Fatalf("Reply = %#v, want InvalidRequest", reply)
+	}
+	if called {
+		t.Error("Handler ran with an invalid deadline")
+	}
+}
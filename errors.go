@@ -28,13 +28,46 @@ const (
 	ServerErrorEnd = -32000
 )
 
+// Implementation-defined error codes, in the server error range.
+const (
+	// ServerNotInitialized indicates a request sent before the client
+	// completed the handshake (see HandshakeConfig).
+	ServerNotInitialized = -32002
+
+	// DeadlineExceeded indicates the deadline the client set for the request
+	// (see MetaDeadline and MetaTimeout) passed before the handler finished.
+	DeadlineExceeded = -32010
+
+	// RequestTimeout indicates the handler did not finish within the server's
+	// timeout (see TimeoutMiddleware).
+	RequestTimeout = -32011
+
+	// ServerOverloaded indicates the server refused the request because too
+	// many earlier calls are still running (see TimeoutConfig.MaxAbandoned).
+	ServerOverloaded = -32012
+
+	// Unauthenticated indicates a request sent before the client presented
+	// a valid token (see AuthConfig).
+	Unauthenticated = -32014
+
+	// Unauthorized indicates the client's identity is not allowed to call
+	// the method (see AuthorizationMiddleware).
+	Unauthorized = -32015
+)
+
 // Standard error messages for common error codes.
 const (
-	parseErrorMessage     = "Parse error"
-	invalidRequestMessage = "Invalid Request"
-	methodNotFoundMessage = "Method not found"
-	invalidParamsMessage  = "Invalid params"
-	internalErrorMessage  = "Internal error"
+	parseErrorMessage       = "Parse error"
+	invalidRequestMessage   = "Invalid Request"
+	methodNotFoundMessage   = "Method not found"
+	invalidParamsMessage    = "Invalid params"
+	internalErrorMessage    = "Internal error"
+	notInitializedMessage   = "Server not initialized"
+	deadlineExceededMessage = "Deadline exceeded"
+	requestTimeoutMessage   = "Request timeout"
+	serverOverloadedMessage = "Server overloaded"
+	unauthenticatedMessage  = "Unauthenticated"
+	unauthorizedMessage     = "Unauthorized"
 )
 
 // NewError creates a new RPCError with the given code, message, and optional data.
@@ -82,6 +115,42 @@ func NewInternalError(data interface{}) *RPCError {
 	return NewError(InternalError, internalErrorMessage, data)
 }
 
+// NewServerNotInitializedError creates a Server Not Initialized Error (-32002).
+// This error is returned for requests sent before the handshake.
+func NewServerNotInitializedError(data interface{}) *RPCError {
+	return NewError(ServerNotInitialized, notInitializedMessage, data)
+}
+
+// NewDeadlineExceededError creates a Deadline Exceeded Error (-32010).
+// This error is returned when the client's deadline for a request passes.
+func NewDeadlineExceededError(data interface{}) *RPCError {
+	return NewError(DeadlineExceeded, deadlineExceededMessage, data)
+}
+
+// NewRequestTimeoutError creates a Request Timeout Error (-32011).
+// This error is returned when a handler exceeds the server's timeout.
+func NewRequestTimeoutError(data interface{}) *RPCError {
+	return NewError(RequestTimeout, requestTimeoutMessage, data)
+}
+
+// NewServerOverloadedError creates a Server Overloaded Error (-32012).
+// This error is returned when the server refuses a request to protect itself.
+func NewServerOverloadedError(data interface{}) *RPCError {
+	return NewError(ServerOverloaded, serverOverloadedMessage, data)
+}
+
+// NewUnauthenticatedError creates an Unauthenticated Error (-32014).
+// This error is returned for requests sent before the client authenticated.
+func NewUnauthenticatedError(data interface{}) *RPCError {
+	return NewError(Unauthenticated, unauthenticatedMessage, data)
+}
+
+// NewUnauthorizedError creates an Unauthorized Error (-32015).
+// This error is returned when an authorization policy denies a request.
+func NewUnauthorizedError(data interface{}) *RPCError {
+	return NewError(Unauthorized, unauthorizedMessage, data)
+}
+
 // WrapError wraps a Go error into a JSON-RPC error with the given code and message.
 // The original error message is included in the data field.
 //
@@ -132,6 +201,18 @@ func ErrorFromCode(code int) *RPCError {
 		return NewInvalidParamsError(nil)
 	case InternalError:
 		return NewInternalError(nil)
+	case ServerNotInitialized:
+		return NewServerNotInitializedError(nil)
+	case DeadlineExceeded:
+		return NewDeadlineExceededError(nil)
+	case RequestTimeout:
+		return NewRequestTimeoutError(nil)
+	case ServerOverloaded:
+		return NewServerOverloadedError(nil)
+	case Unauthenticated:
+		return NewUnauthenticatedError(nil)
+	case Unauthorized:
+		return NewUnauthorizedError(nil)
 	default:
 		if code >= ServerErrorEnd && code <= ServerErrorStart {
 			return NewError(code, "Server error", nil)
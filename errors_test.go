@@ -306,3 +306,58 @@ func TestErrorFromCode(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDeadlineExceededError(t *testing.T) {
+	err := NewDeadlineExceededError(nil)
+	if err.Code != -32010 {
+		t.Errorf("Code = %d, want -32010", err.Code)
+	}
+	if got := ErrorFromCode(DeadlineExceeded); got.Message != "Deadline exceeded" {
+		t.Errorf("ErrorFromCode(DeadlineExceeded).Message = %q, want %q", got.Message, "Deadline exceeded")
+	}
+}
+
+func TestNewTimeoutErrors(t *testing.T) {
+	if err := NewRequestTimeoutError(nil); err.Code != -32011 || err.Message != "Request timeout" {
+		t.Errorf("NewRequestTimeoutError() = %+v", err)
+	}
+	if err := NewServerOverloadedError(nil); err.Code != -32012 || err.Message != "Server overloaded" {
+		t.Errorf("NewServerOverloadedError() = %+v", err)
+	}
+	if got := ErrorFromCode(RequestTimeout); got.Code != RequestTimeout {
+		t.Errorf("ErrorFromCode(RequestTimeout).Code = %d", got.Code)
+	}
+	if got := ErrorFromCode(ServerOverloaded); got.Code != ServerOverloaded {
+		t.Errorf("ErrorFromCode(ServerOverloaded).Code = %d", got.Code)
+	}
+}
+
+func TestNewServerNotInitializedError(t *testing.T) {
+	err := NewServerNotInitializedError(nil)
+	if err.Code != -32002 || err.Message != "Server not initialized" {
+		t.Errorf("NewServerNotInitializedError() = %+v", err)
+	}
+	if got := ErrorFromCode(ServerNotInitialized); got.Message != "Server not initialized" {
+		t.Errorf("ErrorFromCode(ServerNotInitialized).Message = %q", got.Message)
+	}
+}
+
+func TestNewUnauthenticatedError(t *testing.T) {
+	err := NewUnauthenticatedError(nil)
+	if err.Code != -32014 || err.Message != "Unauthenticated" {
+		t.Errorf("NewUnauthenticatedError() = %+v", err)
+	}
+	if got := ErrorFromCode(Unauthenticated); got.Message != "Unauthenticated" {
+		t.Errorf("ErrorFromCode(Unauthenticated).Message = %q", got.Message)
+	}
+}
+
+func TestNewUnauthorizedError(t *testing.T) {
+	err := NewUnauthorizedError(map[string]string{"method": "admin.reset"})
+	if err.Code != -32015 || err.Message != "Unauthorized" {
+		t.Errorf("NewUnauthorizedError() = %+v", err)
+	}
+	if got := ErrorFromCode(Unauthorized); got.Message != "Unauthorized" {
+		t.Errorf("ErrorFromCode(Unauthorized).Message = %q", got.Message)
+	}
+}
@@ -0,0 +1,181 @@
+package jsonrpcipc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// File descriptor passing (Unix sockets only).
+//
+// A message with attached files carries an "fds" member with their count.
+// The descriptors travel as SCM_RIGHTS ancillary data together with the
+// message bytes, and the payload refers to them by index (0 is the first):
+//
+//	{"jsonrpc":"2.0","method":"log.opened","params":{"file":0},"fds":1}
+//
+// The Unix implementation lives in fdpass_unix.go; on Windows every
+// operation returns ErrFilePassingUnsupported.
+
+// maxFDsPerMessage is the most descriptors one message can carry
+// (SCM_MAX_FD on Linux).
+const maxFDsPerMessage = 253
+
+// ErrFilePassingUnsupported is returned when files are sent or received on a
+// connection that is not a Unix socket.
+var ErrFilePassingUnsupported = errors.New("file descriptor passing requires a Unix socket")
+
+// FileResult is a handler result with files attached to the response.
+// Create it with ResultWithFiles.
+type FileResult struct {
+	Result interface{}
+	Files  []*os.File
+}
+
+// ResultWithFiles returns a handler result that sends files along with the
+// response. The files are closed once the response has been sent.
+//
+// Example:
+//
+//	server.RegisterFunc("openLog", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+//	    f, err := os.Open("/var/log/myapp.log")
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return jsonrpcipc.ResultWithFiles(map[string]int{"file": 0}, f), nil
+//	})
+func ResultWithFiles(result interface{}, files ...*os.File) *FileResult {
+	return &FileResult{Result: result, Files: files}
+}
+
+// fileCodec is implemented by codecs that may carry file descriptors.
+type fileCodec interface {
+	WriteJSONWithFiles(v interface{}, files []*os.File) error
+	writeMessageWithFiles(data []byte, files []*os.File) error
+	takeFiles(n int) ([]*os.File, error)
+	// discardFiles closes all received files no message has claimed
+	discardFiles()
+	// supportsFiles reports whether the underlying transport is a Unix socket
+	supportsFiles() bool
+}
+
+// filesSupported returns codec as a fileCodec if it can carry files.
+func filesSupported(codec Codec) (fileCodec, bool) {
+	fc, ok := codec.(fileCodec)
+	if !ok || !fc.supportsFiles() {
+		return nil, false
+	}
+	return fc, true
+}
+
+// fileReply is a response to be sent with attached files.
+type fileReply struct {
+	response *Response
+	files    []*os.File
+}
+
+// closeFiles closes all files, ignoring errors.
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// ReadMessageWithFiles reads the next message along with the files it
+// declares in its "fds" member (the sum over all items for a batch).
+// The caller owns the returned files and must close them.
+//
+// This is the receiving side of Connection.NotifyWithFDs and ResultWithFiles
+// for Go clients.
+func (c *LineDelimitedCodec) ReadMessageWithFiles() ([]byte, []*os.File, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	data, err := c.readMessage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := c.takeFiles(countFDs(data))
+	if err != nil {
+		return data, nil, err
+	}
+	return data, files, nil
+}
+
+// WriteJSONWithFiles marshals v and sends it with files attached.
+// v must declare the files in its "fds" member (for example Request.FDs),
+// so the receiver knows how many descriptors belong to the message.
+//
+// The caller keeps ownership of the files.
+func (c *LineDelimitedCodec) WriteJSONWithFiles(v interface{}, files []*os.File) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.writeMessageWithFiles(data, files)
+}
+
+// writeMessageWithFiles writes a raw message with files attached.
+func (c *LineDelimitedCodec) writeMessageWithFiles(data []byte, files []*os.File) error {
+	if len(files) == 0 {
+		return c.WriteMessage(data)
+	}
+	if len(files) > maxFDsPerMessage {
+		return fmt.Errorf("cannot send %d files in one message (maximum %d)", len(files), maxFDsPerMessage)
+	}
+	return c.sendWithFiles(data, files)
+}
+
+// supportsFiles reports whether the codec runs over a Unix socket.
+func (c *LineDelimitedCodec) supportsFiles() bool {
+	return c.fds != nil
+}
+
+// takeFiles removes the next n received files from the queue.
+func (c *LineDelimitedCodec) takeFiles(n int) ([]*os.File, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if c.fds == nil {
+		return nil, ErrFilePassingUnsupported
+	}
+	return c.fds.take(n)
+}
+
+// discardFiles closes all received files no message has claimed.
+func (c *LineDelimitedCodec) discardFiles() {
+	if c.fds != nil {
+		c.fds.discard()
+	}
+}
+
+// countFDs returns the number of files a raw message declares.
+func countFDs(data []byte) int {
+	var envelope struct {
+		FDs int `json:"fds"`
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []json.RawMessage
+		if json.Unmarshal(trimmed, &items) != nil {
+			return 0
+		}
+		total := 0
+		for _, item := range items {
+			envelope.FDs = 0
+			if json.Unmarshal(item, &envelope) == nil && envelope.FDs > 0 {
+				total += envelope.FDs
+			}
+		}
+		return total
+	}
+
+	if json.Unmarshal(trimmed, &envelope) != nil || envelope.FDs < 0 {
+		return 0
+	}
+	return envelope.FDs
+}
@@ -0,0 +1,88 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCountFDs(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"none", `{"jsonrpc":"2.0","method":"a"}`, 0},
+		{"single", `{"jsonrpc":"2.0","method":"a","fds":2}`, 2},
+		{"batch", `[{"method":"a","fds":1},{"method":"b"},{"method":"c","fds":3}]`, 4},
+		{"negative", `{"method":"a","fds":-1}`, 0},
+		{"invalid json", `{invalid`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countFDs([]byte(tt.data)); got != tt.want {
+				t.Errorf("countFDs(%s) = %d, want %d", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnection_NotifyWithFDs_Unsupported(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn2.Close()
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+	defer connection.Close()
+
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	err = connection.NotifyWithFDs("file", nil, []*os.File{f})
+	if !errors.Is(err, ErrFilePassingUnsupported) {
+		t.Errorf("NotifyWithFDs() error = %v, want ErrFilePassingUnsupported", err)
+	}
+}
+
+func TestConnection_FileResult_Unsupported(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn2.Close()
+
+	registry := NewHandlerRegistry()
+	registry.Register("open", HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			return nil, err
+		}
+		return ResultWithFiles("ok", f), nil
+	}))
+
+	connection := newConnection(conn1, registry, nil, nil)
+	defer connection.Close()
+
+	reply := connection.dispatch(&Request{JSONRPC: "2.0", Method: "open", ID: 1}, nil)
+	errResp, ok := reply.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("dispatch() = %T, want *ErrorResponse", reply)
+	}
+	if errResp.Error.Code != InternalError {
+		t.Errorf("Error code = %d, want InternalError", errResp.Error.Code)
+	}
+}
+
+func TestFilesFromContext(t *testing.T) {
+	if files := FilesFromContext(context.Background()); files != nil {
+		t.Errorf("FilesFromContext() = %v, want nil", files)
+	}
+
+	files := []*os.File{os.Stdin}
+	ctx := WithFiles(context.Background(), files)
+	if got := FilesFromContext(ctx); len(got) != 1 || got[0] != os.Stdin {
+		t.Errorf("FilesFromContext() = %v, want %v", got, files)
+	}
+}
@@ -0,0 +1,157 @@
+//go:build !windows
+
+package jsonrpcipc
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+// maxPendingFDs bounds the descriptors received but not yet claimed by a
+// message, so a misbehaving peer cannot exhaust the descriptor table.
+const maxPendingFDs = 2 * maxFDsPerMessage
+
+// fdReader reads from a Unix socket and queues the file descriptors that
+// arrive as SCM_RIGHTS ancillary data.
+//
+// Descriptors are sent with the first bytes of their message, so they are
+// always queued by the time the message has been read completely, and the
+// queue order matches the message order.
+type fdReader struct {
+	conn *net.UnixConn
+	oob  []byte
+
+	mu    sync.Mutex // Protects files
+	files []*os.File
+}
+
+// newFDReader returns an fdReader for Unix socket connections, or nil.
+func newFDReader(conn io.ReadWriteCloser) *fdReader {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	return &fdReader{
+		conn: unixConn,
+		oob:  make([]byte, syscall.CmsgSpace(maxFDsPerMessage*4)),
+	}
+}
+
+// Read reads data and queues any received descriptors.
+func (r *fdReader) Read(p []byte) (int, error) {
+	n, oobn, flags, _, err := r.conn.ReadMsgUnix(p, r.oob)
+	if n < 0 {
+		// ReadMsgUnix may report -1 on error; io.Reader must not
+		n = 0
+	}
+	if oobn > 0 {
+		if collectErr := r.collect(r.oob[:oobn]); collectErr != nil && err == nil {
+			err = collectErr
+		}
+	}
+	if flags&syscall.MSG_CTRUNC != 0 && err == nil {
+		// The kernel dropped descriptors; the queue no longer matches the messages
+		err = fmt.Errorf("received file descriptors were truncated")
+	}
+	return n, err
+}
+
+// collect parses control messages and queues the descriptors they carry.
+func (r *fdReader) collect(oob []byte) error {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return fmt.Errorf("failed to parse control message: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range msgs {
+		fds, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			continue // Not SCM_RIGHTS
+		}
+		for _, fd := range fds {
+			syscall.CloseOnExec(fd)
+			r.files = append(r.files, os.NewFile(uintptr(fd), fmt.Sprintf("fd:%d", fd)))
+		}
+	}
+
+	if len(r.files) > maxPendingFDs {
+		closeFiles(r.files)
+		r.files = nil
+		return fmt.Errorf("too many unclaimed file descriptors")
+	}
+	return nil
+}
+
+// take removes the next n files from the queue.
+func (r *fdReader) take(n int) ([]*os.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n > len(r.files) {
+		return nil, fmt.Errorf("message declares %d file descriptors but %d were received", n, len(r.files))
+	}
+
+	files := r.files[:n:n]
+	r.files = r.files[n:]
+	return files, nil
+}
+
+// discard closes all queued files.
+func (r *fdReader) discard() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	closeFiles(r.files)
+	r.files = nil
+}
+
+// sendWithFiles sends a line-delimited message with files attached
+// as SCM_RIGHTS ancillary data.
+func (c *LineDelimitedCodec) sendWithFiles(data []byte, files []*os.File) error {
+	if c.fds == nil {
+		return ErrFilePassingUnsupported
+	}
+
+	// Fd would put the files into blocking mode
+	fds := make([]int, len(files))
+	for i, f := range files {
+		raw, err := f.SyscallConn()
+		if err != nil {
+			return fmt.Errorf("cannot send file %s: %w", f.Name(), err)
+		}
+		if err := raw.Control(func(fd uintptr) { fds[i] = int(fd) }); err != nil {
+			return fmt.Errorf("cannot send file %s: %w", f.Name(), err)
+		}
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// Earlier buffered messages must not end up after this one
+	if err := c.writer.Flush(); err != nil {
+		return fmt.Errorf("flush error: %w", err)
+	}
+
+	line := append(data[:len(data):len(data)], '\n')
+	n, _, err := c.fds.conn.WriteMsgUnix(line, syscall.UnixRights(fds...), nil)
+	runtime.KeepAlive(files)
+	if err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+
+	// The descriptors went out with the first chunk; send the rest as plain data
+	if n < len(line) {
+		if _, err := c.fds.conn.Write(line[n:]); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+	return nil
+}
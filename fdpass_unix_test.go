@@ -0,0 +1,312 @@
+//go:build !windows
+
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// unixConnPair returns two connected Unix stream sockets.
+func unixConnPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	t.Helper()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("Socketpair() error = %v", err)
+	}
+
+	conns := make([]*net.UnixConn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		conn, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("FileConn() error = %v", err)
+		}
+		conns[i] = conn.(*net.UnixConn)
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conns[0], conns[1]
+}
+
+// pipeWithContent returns the read end of a pipe holding content.
+func pipeWithContent(t *testing.T, content string) *os.File {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	w.WriteString(content)
+	w.Close()
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// readAllAndClose reads a received file to the end and closes it.
+func readAllAndClose(t *testing.T, f *os.File) string {
+	t.Helper()
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return string(data)
+}
+
+func TestLineDelimitedCodec_FilesRoundTrip(t *testing.T) {
+	a, b := unixConnPair(t)
+	sender := NewCodec(a)
+	receiver := NewCodec(b)
+
+	// A plain message first, to check ordering with buffered writes
+	if err := sender.WriteJSON(&Notification{JSONRPC: "2.0", Method: "plain"}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	files := []*os.File{pipeWithContent(t, "first"), pipeWithContent(t, "second")}
+	if err := sender.WriteJSONWithFiles(&Notification{JSONRPC: "2.0", Method: "files", FDs: 2}, files); err != nil {
+		t.Fatalf("WriteJSONWithFiles() error = %v", err)
+	}
+
+	data, received, err := receiver.ReadMessageWithFiles()
+	if err != nil {
+		t.Fatalf("ReadMessageWithFiles() error = %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("Plain message received %d files, want 0", len(received))
+	}
+
+	data, received, err = receiver.ReadMessageWithFiles()
+	if err != nil {
+		t.Fatalf("ReadMessageWithFiles() error = %v", err)
+	}
+	var notif Notification
+	json.Unmarshal(data, &notif)
+	if notif.Method != "files" || notif.FDs != 2 {
+		t.Errorf("Notification = %+v, want method files with 2 fds", notif)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Received %d files, want 2", len(received))
+	}
+	if got := readAllAndClose(t, received[0]); got != "first" {
+		t.Errorf("File 0 content = %q, want first", got)
+	}
+	if got := readAllAndClose(t, received[1]); got != "second" {
+		t.Errorf("File 1 content = %q, want second", got)
+	}
+}
+
+func TestLineDelimitedCodec_SendKeepsFilesNonBlocking(t *testing.T) {
+	a, b := unixConnPair(t)
+	sender := NewCodec(a)
+	receiver := NewCodec(b)
+
+	f := pipeWithContent(t, "data")
+	if err := sender.WriteJSONWithFiles(&Notification{JSONRPC: "2.0", Method: "files", FDs: 1}, []*os.File{f}); err != nil {
+		t.Fatalf("WriteJSONWithFiles() error = %v", err)
+	}
+	_, files, err := receiver.ReadMessageWithFiles()
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ReadMessageWithFiles() = %d files, %v", len(files), err)
+	}
+	files[0].Close()
+
+	raw, err := f.SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn() error = %v", err)
+	}
+	var flags uintptr
+	raw.Control(func(fd uintptr) {
+		flags, _, _ = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+	})
+	if flags&syscall.O_NONBLOCK == 0 {
+		t.Error("Sent file was switched to blocking mode")
+	}
+}
+
+func TestLineDelimitedCodec_MissingFiles(t *testing.T) {
+	a, b := unixConnPair(t)
+	sender := NewCodec(a)
+	receiver := NewCodec(b)
+
+	// Declares a file but sends none
+	sender.WriteJSON(&Notification{JSONRPC: "2.0", Method: "files", FDs: 1})
+
+	if _, _, err := receiver.ReadMessageWithFiles(); err == nil {
+		t.Error("ReadMessageWithFiles() should fail when declared files are missing")
+	}
+}
+
+// startFileServer starts a server on a Unix socket with file-passing
+// handlers and returns a client codec connected to it.
+func startFileServer(t *testing.T) *LineDelimitedCodec {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "files.sock")
+	server, err := NewServer(ServerConfig{SocketPath: socketPath})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	server.RegisterFunc("open", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		w.WriteString("from server")
+		w.Close()
+		return ResultWithFiles(map[string]int{"file": 0}, r), nil
+	})
+	server.RegisterFunc("read", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		files := FilesFromContext(ctx)
+		if len(files) != 1 {
+			return nil, NewInvalidParamsError("expected one file")
+		}
+		defer files[0].Close()
+		data, err := io.ReadAll(files[0])
+		return string(data), err
+	})
+	server.RegisterFunc("stream", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		w.WriteString("streamed")
+		w.Close()
+		if err := ConnectionFromContext(ctx).NotifyWithFDs("stream.opened", map[string]int{"pipe": 0}, []*os.File{r}); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	})
+
+	go server.Start()
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Addr() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Server did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewCodec(conn)
+}
+
+func TestServer_FileResult(t *testing.T) {
+	client := startFileServer(t)
+
+	client.WriteJSON(&Request{JSONRPC: "2.0", Method: "open", ID: 1})
+
+	data, files, err := client.ReadMessageWithFiles()
+	if err != nil {
+		t.Fatalf("ReadMessageWithFiles() error = %v", err)
+	}
+	var resp Response
+	json.Unmarshal(data, &resp)
+	if resp.FDs != 1 || len(files) != 1 {
+		t.Fatalf("Response fds = %d with %d files, want 1", resp.FDs, len(files))
+	}
+	if got := readAllAndClose(t, files[0]); got != "from server" {
+		t.Errorf("File content = %q, want %q", got, "from server")
+	}
+}
+
+func TestServer_RequestWithFiles(t *testing.T) {
+	client := startFileServer(t)
+
+	f := pipeWithContent(t, "from client")
+	if err := client.WriteJSONWithFiles(&Request{JSONRPC: "2.0", Method: "read", ID: 1, FDs: 1}, []*os.File{f}); err != nil {
+		t.Fatalf("WriteJSONWithFiles() error = %v", err)
+	}
+
+	var resp Response
+	if err := client.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Result != "from client" {
+		t.Errorf("Result = %v, want %q", resp.Result, "from client")
+	}
+}
+
+func TestServer_NotifyWithFDs(t *testing.T) {
+	client := startFileServer(t)
+
+	client.WriteJSON(&Request{JSONRPC: "2.0", Method: "stream", ID: 1})
+
+	data, files, err := client.ReadMessageWithFiles()
+	if err != nil {
+		t.Fatalf("ReadMessageWithFiles() error = %v", err)
+	}
+	var notif Notification
+	json.Unmarshal(data, &notif)
+	if notif.Method != "stream.opened" || len(files) != 1 {
+		t.Fatalf("Notification %q with %d files, want stream.opened with 1", notif.Method, len(files))
+	}
+	if got := readAllAndClose(t, files[0]); got != "streamed" {
+		t.Errorf("File content = %q, want streamed", got)
+	}
+
+	var resp Response
+	if err := client.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Result != "ok" {
+		t.Errorf("Result = %v, want ok", resp.Result)
+	}
+}
+
+func TestServer_RequestMissingFiles(t *testing.T) {
+	client := startFileServer(t)
+
+	// Declares a file without attaching one
+	client.WriteJSON(&Request{JSONRPC: "2.0", Method: "read", ID: 1, FDs: 1})
+
+	var resp Message
+	if err := client.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != InvalidRequest {
+		t.Errorf("Error = %v, want InvalidRequest", resp.Error)
+	}
+}
+
+func TestServer_UnparsableLineWithFiles(t *testing.T) {
+	client := startFileServer(t)
+
+	stray := pipeWithContent(t, "stray")
+	if err := client.writeMessageWithFiles([]byte(`{"jsonrpc":"2.0","method":"read","fds":1`), []*os.File{stray}); err != nil {
+		t.Fatalf("writeMessageWithFiles() error = %v", err)
+	}
+	var resp Message
+	if err := client.ReadJSON(&resp); err != nil || resp.Error == nil || resp.Error.Code != ParseError {
+		t.Fatalf("Unparsable line = %+v, %v, want ParseError", resp.Error, err)
+	}
+
+	// The file of the unparsable line is not handed to the next request
+	f := pipeWithContent(t, "from client")
+	if err := client.WriteJSONWithFiles(&Request{JSONRPC: "2.0", Method: "read", ID: 2, FDs: 1}, []*os.File{f}); err != nil {
+		t.Fatalf("WriteJSONWithFiles() error = %v", err)
+	}
+	if err := client.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if string(resp.Result) != `"from client"` {
+		t.Errorf("Result = %s, want the second file's content", resp.Result)
+	}
+}
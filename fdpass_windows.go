@@ -0,0 +1,34 @@
+//go:build windows
+
+package jsonrpcipc
+
+import (
+	"io"
+	"os"
+)
+
+// fdReader is never created on Windows, which has no SCM_RIGHTS.
+type fdReader struct{}
+
+// newFDReader always returns nil on Windows.
+func newFDReader(conn io.ReadWriteCloser) *fdReader {
+	return nil
+}
+
+// Read is never called on Windows.
+func (r *fdReader) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+// take always fails on Windows.
+func (r *fdReader) take(n int) ([]*os.File, error) {
+	return nil, ErrFilePassingUnsupported
+}
+
+// discard is a no-op on Windows.
+func (r *fdReader) discard() {}
+
+// sendWithFiles always fails on Windows.
+func (c *LineDelimitedCodec) sendWithFiles(data []byte, files []*os.File) error {
+	return ErrFilePassingUnsupported
+}
@@ -0,0 +1,75 @@
+package jsonrpcipc
+
+// Dot-separated name patterns, shared by topic subscriptions and method rules.
+//
+// Names such as topics and method names are dot-separated ("build.step.done").
+// In a pattern:
+//   - "*" matches any run of characters within one segment (no dots),
+//     so "build.*" matches "build.started" but not "build.step.done"
+//   - "**" matches any run of characters, dots included,
+//     so "build.**" matches both, and "**" matches every name
+//   - every other character matches itself
+
+// globToken is one element of a parsed pattern.
+type globToken struct {
+	kind byte // 0 for a literal byte, '*' or 'D' (for "**")
+	char byte
+}
+
+// matchGlob reports whether name matches pattern. It runs in
+// O(len(pattern) * len(name)) time, so client-supplied patterns are safe.
+func matchGlob(pattern, name string) bool {
+	tokens := make([]globToken, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			tokens = append(tokens, globToken{kind: 'D'})
+			i++
+		case pattern[i] == '*':
+			tokens = append(tokens, globToken{kind: '*'})
+		default:
+			tokens = append(tokens, globToken{char: pattern[i]})
+		}
+	}
+
+	// next[j] reports whether tokens[i+1:] matches name[j:]; cur is for tokens[i:]
+	next := make([]bool, len(name)+1)
+	cur := make([]bool, len(name)+1)
+	next[len(name)] = true
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		tok := tokens[i]
+		for j := len(name); j >= 0; j-- {
+			switch tok.kind {
+			case 'D':
+				cur[j] = next[j] || (j < len(name) && cur[j+1])
+			case '*':
+				cur[j] = next[j] || (j < len(name) && name[j] != '.' && cur[j+1])
+			default:
+				cur[j] = j < len(name) && name[j] == tok.char && next[j+1]
+			}
+		}
+		next, cur = cur, next
+	}
+	return next[0]
+}
+
+// isGlob reports whether pattern contains wildcards.
+func isGlob(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '*' {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether name equals or matches any of patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if pattern == name || (isGlob(pattern) && matchGlob(pattern, name)) {
+			return true
+		}
+	}
+	return false
+}
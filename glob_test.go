@@ -0,0 +1,58 @@
+package jsonrpcipc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"build", "build", true},
+		{"build", "builds", false},
+		{"build.*", "build.started", true},
+		{"build.*", "build.step.done", false},
+		{"build.*", "build", false},
+		{"build.*", "build.", true},
+		{"build.**", "build.step.done", true},
+		{"build.**", "deploy.started", false},
+		{"*.done", "build.done", true},
+		{"*.done", "build.step.done", false},
+		{"**.done", "build.step.done", true},
+		{"build*", "builder", true},
+		{"build*", "build.x", false},
+		{"**", "anything.at.all", true},
+		{"**", "", true},
+		{"*", "single", true},
+		{"*", "two.segments", false},
+		{"", "", true},
+		{"", "x", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlob_Pathological(t *testing.T) {
+	// Backtracking matchers take exponential time on this input
+	pattern := strings.Repeat("**a", 20) + "b"
+	name := strings.Repeat("a", 200)
+	if matchGlob(pattern, name) {
+		t.Error("matchGlob() matched a name without the trailing b")
+	}
+}
+
+func TestIsGlob(t *testing.T) {
+	if isGlob("build.started") {
+		t.Error("isGlob(build.started) = true, want false")
+	}
+	if !isGlob("build.*") {
+		t.Error("isGlob(build.*) = false, want true")
+	}
+}
@@ -0,0 +1,181 @@
+package jsonrpcipc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Groups are named sets of connections for targeted notifications, such as
+// every window of one workspace. Connections join them from handlers or
+// hooks such as OnConnect, and leave them when they disconnect:
+//
+//	func openWorkspace(ctx context.Context, params json.RawMessage) (interface{}, error) {
+//	    conn := jsonrpc.ConnectionFromContext(ctx)
+//	    conn.Join("workspace:" + id)
+//	    return nil, nil
+//	}
+//
+//	server.BroadcastTo("workspace:"+id, "file.changed", change)
+//
+// Unlike topic subscriptions, groups are managed by the server only;
+// clients cannot join them.
+
+// Join adds the connection to a group. Joining a group twice has no further
+// effect. The connection leaves all its groups when it closes.
+func (c *Connection) Join(group string) error {
+	if group == "" {
+		return fmt.Errorf("group must not be empty")
+	}
+	if c.IsClosed() {
+		return errConnectionClosed
+	}
+
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+
+	if c.groups == nil {
+		c.groups = make(map[string]struct{})
+	}
+	c.groups[group] = struct{}{}
+	return nil
+}
+
+// Leave removes the connection from a group.
+func (c *Connection) Leave(group string) {
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+	delete(c.groups, group)
+}
+
+// Groups returns the groups the connection belongs to, sorted.
+func (c *Connection) Groups() []string {
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+
+	groups := make([]string, 0, len(c.groups))
+	for group := range c.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// InGroup reports whether the connection belongs to group.
+func (c *Connection) InGroup(group string) bool {
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+	_, ok := c.groups[group]
+	return ok
+}
+
+// leaveAll removes the connection from all its groups.
+func (c *Connection) leaveAll() {
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+	c.groups = nil
+}
+
+// BroadcastTo sends a notification to every connection in group.
+//
+// Returns the number of connections the notification was sent to.
+func (bm *BroadcastManager) BroadcastTo(group, method string, params interface{}) int {
+	return bm.BroadcastFilter(inGroup(group), method, params)
+}
+
+// BroadcastExcept sends a notification to every connection but except,
+// typically the client whose request caused it.
+//
+// Returns the number of connections the notification was sent to.
+func (bm *BroadcastManager) BroadcastExcept(except *Connection, method string, params interface{}) int {
+	return bm.BroadcastFilter(allBut(except), method, params)
+}
+
+// inGroup selects the connections in group.
+func inGroup(group string) func(*Connection) bool {
+	return func(conn *Connection) bool {
+		return conn.InGroup(group)
+	}
+}
+
+// allBut selects every connection but except.
+func allBut(except *Connection) func(*Connection) bool {
+	return func(conn *Connection) bool {
+		return conn != except
+	}
+}
+
+// GroupMembers returns the connections in group, ordered by connection ID.
+func (bm *BroadcastManager) GroupMembers(group string) []*Connection {
+	var members []*Connection
+
+	bm.connections.Range(func(key, value interface{}) bool {
+		conn := key.(*Connection)
+		if conn.InGroup(group) {
+			members = append(members, conn)
+		}
+		return true
+	})
+
+	sort.Slice(members, func(i, j int) bool { return members[i].id < members[j].id })
+	return members
+}
+
+// Groups returns the names of the groups with at least one member, sorted.
+func (bm *BroadcastManager) Groups() []string {
+	seen := make(map[string]struct{})
+
+	bm.connections.Range(func(key, value interface{}) bool {
+		for _, group := range key.(*Connection).Groups() {
+			seen[group] = struct{}{}
+		}
+		return true
+	})
+
+	groups := make([]string, 0, len(seen))
+	for group := range seen {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// BroadcastTo sends a notification to every client in group.
+//
+// Example:
+//
+//	server.BroadcastTo("workspace:"+id, "file.changed", map[string]string{"path": path})
+//
+// Returns the number of clients the notification was queued for.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) BroadcastTo(group, method string, params interface{}) int {
+	return s.BroadcastFilter(inGroup(group), method, params)
+}
+
+// BroadcastExcept sends a notification to every client but except, such as
+// the client whose change the notification announces.
+//
+// Example:
+//
+//	func rename(ctx context.Context, params json.RawMessage) (interface{}, error) {
+//	    // ... rename ...
+//	    server.BroadcastExcept(jsonrpc.ConnectionFromContext(ctx), "renamed", params)
+//	    return nil, nil
+//	}
+//
+// Returns the number of clients the notification was queued for.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) BroadcastExcept(except *Connection, method string, params interface{}) int {
+	return s.BroadcastFilter(allBut(except), method, params)
+}
+
+// GroupMembers returns the connected clients in group, ordered by connection ID.
+func (s *Server) GroupMembers(group string) []*Connection {
+	return s.broadcast.GroupMembers(group)
+}
+
+// Groups returns the names of the groups that have connected members, sorted.
+func (s *Server) Groups() []string {
+	return s.broadcast.Groups()
+}
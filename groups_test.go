@@ -0,0 +1,227 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestConnection_Join(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+
+	if err := connection.Join(""); err == nil {
+		t.Error("Join(\"\") should fail")
+	}
+
+	connection.Join("workspace:b")
+	connection.Join("workspace:a")
+	connection.Join("workspace:a")
+
+	if got := connection.Groups(); len(got) != 2 || got[0] != "workspace:a" || got[1] != "workspace:b" {
+		t.Errorf("Groups() = %v, want [workspace:a workspace:b]", got)
+	}
+	if !connection.InGroup("workspace:a") {
+		t.Error("InGroup(workspace:a) = false after Join")
+	}
+
+	connection.Leave("workspace:a")
+	if connection.InGroup("workspace:a") {
+		t.Error("InGroup(workspace:a) = true after Leave")
+	}
+
+	connection.Close()
+	if got := connection.Groups(); len(got) != 0 {
+		t.Errorf("Groups() after Close = %v, want none", got)
+	}
+	if err := connection.Join("workspace:a"); err == nil {
+		t.Error("Join() on a closed connection should fail")
+	}
+}
+
+func TestConnection_JoinFromHandler(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("open", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var workspace string
+		json.Unmarshal(params, &workspace)
+		return nil, ConnectionFromContext(ctx).Join("workspace:" + workspace)
+	})
+	connection := newConnection(conn1, registry, nil, nil)
+
+	connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"open","params":"x","id":1}`))
+
+	if !connection.InGroup("workspace:x") {
+		t.Errorf("Groups() = %v, want [workspace:x]", connection.Groups())
+	}
+}
+
+// groupTestClient is a connection in a BroadcastManager whose notifications
+// are collected from its peer.
+type groupTestClient struct {
+	conn     *Connection
+	received chan string
+}
+
+func newGroupTestClients(t *testing.T, bm *BroadcastManager, n int) []*groupTestClient {
+	t.Helper()
+
+	clients := make([]*groupTestClient, n)
+	for i := range clients {
+		conn1, conn2 := newMockConnPair()
+		t.Cleanup(func() {
+			conn1.Close()
+			conn2.Close()
+		})
+
+		client := &groupTestClient{
+			conn:     newConnection(conn1, NewHandlerRegistry(), nil, nil),
+			received: make(chan string, 10),
+		}
+		bm.Add(client.conn)
+
+		go func() {
+			codec := NewCodec(conn2)
+			for {
+				var notif Notification
+				if err := codec.ReadJSON(&notif); err != nil {
+					return
+				}
+				client.received <- notif.Method
+			}
+		}()
+		clients[i] = client
+	}
+	return clients
+}
+
+// expectNotified checks which clients received method.
+func expectNotified(t *testing.T, clients []*groupTestClient, method string, want ...bool) {
+	t.Helper()
+
+	for i, client := range clients {
+		select {
+		case got := <-client.received:
+			if !want[i] {
+				t.Errorf("Client %d received %q, want nothing", i, got)
+			} else if got != method {
+				t.Errorf("Client %d received %q, want %q", i, got, method)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if want[i] {
+				t.Errorf("Client %d did not receive %q", i, method)
+			}
+		}
+	}
+}
+
+func TestBroadcastManager_BroadcastTo(t *testing.T) {
+	bm := NewBroadcastManager()
+	clients := newGroupTestClients(t, bm, 3)
+
+	clients[0].conn.Join("workspace:a")
+	clients[1].conn.Join("workspace:a")
+	clients[2].conn.Join("workspace:b")
+
+	if count := bm.BroadcastTo("workspace:a", "file.changed", nil); count != 2 {
+		t.Errorf("BroadcastTo() count = %d, want 2", count)
+	}
+	expectNotified(t, clients, "file.changed", true, true, false)
+
+	if count := bm.BroadcastTo("workspace:none", "file.changed", nil); count != 0 {
+		t.Errorf("BroadcastTo() to an empty group count = %d, want 0", count)
+	}
+}
+
+func TestBroadcastManager_BroadcastExcept(t *testing.T) {
+	bm := NewBroadcastManager()
+	clients := newGroupTestClients(t, bm, 3)
+
+	if count := bm.BroadcastExcept(clients[1].conn, "renamed", nil); count != 2 {
+		t.Errorf("BroadcastExcept() count = %d, want 2", count)
+	}
+	expectNotified(t, clients, "renamed", true, false, true)
+}
+
+func TestBroadcastManager_GroupMembers(t *testing.T) {
+	bm := NewBroadcastManager()
+	clients := newGroupTestClients(t, bm, 3)
+
+	clients[2].conn.Join("workspace:a")
+	clients[0].conn.Join("workspace:a")
+	clients[1].conn.Join("workspace:b")
+
+	members := bm.GroupMembers("workspace:a")
+	if len(members) != 2 || members[0] != clients[0].conn || members[1] != clients[2].conn {
+		t.Errorf("GroupMembers(workspace:a) = %v, want clients 0 and 2", members)
+	}
+	if got := bm.Groups(); len(got) != 2 || got[0] != "workspace:a" || got[1] != "workspace:b" {
+		t.Errorf("Groups() = %v, want [workspace:a workspace:b]", got)
+	}
+
+	// A disconnected client leaves its groups
+	clients[1].conn.Close()
+	bm.Remove(clients[1].conn)
+	if got := bm.Groups(); len(got) != 1 || got[0] != "workspace:a" {
+		t.Errorf("Groups() after disconnect = %v, want [workspace:a]", got)
+	}
+}
+
+func TestServer_Groups(t *testing.T) {
+	joined := make(chan *Connection, 2)
+	server, addr := startNetworkServer(t, ServerConfig{
+		SocketPath: "tcp://127.0.0.1:0",
+		OnConnect: func(conn *Connection) {
+			joined <- conn
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		client, err := Dial(addr)
+		if err != nil {
+			t.Fatalf("Dial(%q) error: %v", addr, err)
+		}
+		defer client.Close()
+	}
+
+	conn := <-joined
+	<-joined
+	if err := conn.Join("admins"); err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	if members := server.GroupMembers("admins"); len(members) != 1 || members[0] != conn {
+		t.Errorf("GroupMembers(admins) = %v, want one member", members)
+	}
+	if count := server.BroadcastTo("admins", "alert", nil); count != 1 {
+		t.Errorf("BroadcastTo() count = %d, want 1", count)
+	}
+	if count := server.BroadcastExcept(conn, "hello", nil); count != 1 {
+		t.Errorf("BroadcastExcept() count = %d, want 1", count)
+	}
+	if got := server.Metrics().broadcasts.Load(); got != 2 {
+		t.Errorf("Metrics broadcasts = %d, want 2", got)
+	}
+
+	conn.Leave("admins")
+	if got := server.Groups(); len(got) != 0 {
+		t.Errorf("Groups() after Leave = %v, want none", got)
+	}
+
+	// Membership ends with the connection
+	conn.Join("admins")
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.GroupMembers("admins")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Disconnected client is still a group member")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
 )
 
 // Handler processes a JSON-RPC request and returns a result or error.
@@ -175,6 +177,21 @@ const (
 
 	// contextKeyConnection stores the current connection in the context.
 	contextKeyConnection contextKey = "jsonrpc.connection"
+
+	// contextKeyFiles stores the files attached to the current request in the context.
+	contextKeyFiles contextKey = "jsonrpc.files"
+
+	// contextKeyLogger stores the request-scoped logger in the context.
+	contextKeyLogger contextKey = "jsonrpc.logger"
+
+	// contextKeyTrace stores the W3C trace context of the current request in the context.
+	contextKeyTrace contextKey = "jsonrpc.trace"
+
+	// contextKeyMeta stores the "meta" member of the current request in the context.
+	contextKeyMeta contextKey = "jsonrpc.meta"
+
+	// contextKeyResponseMeta stores the metadata collected for the response in the context.
+	contextKeyResponseMeta contextKey = "jsonrpc.response_meta"
 )
 
 // MethodFromContext retrieves the JSON-RPC method name from the context.
@@ -215,3 +232,31 @@ func ConnectionFromContext(ctx context.Context) *Connection {
 func WithConnection(ctx context.Context, conn *Connection) context.Context {
 	return context.WithValue(ctx, contextKeyConnection, conn)
 }
+
+// FilesFromContext retrieves the files attached to the current request
+// (see Request.FDs). The handler owns the files and must close them.
+// Returns nil if the request carried no files.
+func FilesFromContext(ctx context.Context) []*os.File {
+	files, _ := ctx.Value(contextKeyFiles).([]*os.File)
+	return files
+}
+
+// WithFiles adds the files attached to a request to the context.
+func WithFiles(ctx context.Context, files []*os.File) context.Context {
+	return context.WithValue(ctx, contextKeyFiles, files)
+}
+
+// LoggerFromContext retrieves the request-scoped logger from the context.
+// Inside a handler it carries method, request_id and conn_id attributes.
+// Returns slog.Default() if not found.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKeyLogger).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithLogger adds a request-scoped logger to the context.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKeyLogger, logger)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"testing"
 )
 
@@ -533,3 +534,15 @@ func TestTypedHandler_ContextPropagation(t *testing.T) {
 		t.Errorf("Method in handler = %q, want %q", receivedMethod, "my.method")
 	}
 }
+
+func TestLoggerFromContext(t *testing.T) {
+	if got := LoggerFromContext(context.Background()); got != slog.Default() {
+		t.Error("LoggerFromContext() without logger should return slog.Default()")
+	}
+
+	logger, _ := newBufferLogger()
+	ctx := WithLogger(context.Background(), logger)
+	if got := LoggerFromContext(ctx); got != logger {
+		t.Error("LoggerFromContext() did not return the stored logger")
+	}
+}
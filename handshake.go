@@ -0,0 +1,185 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// InitializeMethod is the handshake method clients call first when
+// ServerConfig.Handshake is set. Until it succeeds, every other request is
+// refused with ServerNotInitialized:
+//
+//	→ {"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"2","clientInfo":{"name":"vscode-ext","version":"1.4.0"},"capabilities":["progress","cancel"]},"id":1}
+//	← {"jsonrpc":"2.0","result":{"protocolVersion":"2","serverInfo":{"name":"mydaemon","version":"3.1.0"},"capabilities":["progress"]},"id":1}
+//
+// With a handshake configured, the server handles this method itself, even
+// if a handler is registered under the same name.
+const InitializeMethod = "initialize"
+
+// HandshakeConfig enables the initialize handshake, in which client and
+// server agree on a protocol version and optional features.
+type HandshakeConfig struct {
+	// ProtocolVersions lists the protocol versions the server speaks.
+	// Clients asking for another version are refused. If empty, any version
+	// is accepted.
+	ProtocolVersions []string
+
+	// Capabilities lists the optional features the server supports. A
+	// connection's capabilities are those listed by both client and server.
+	Capabilities []string
+
+	// ServerInfo identifies the server to clients.
+	ServerInfo ImplementationInfo
+
+	// Accept is called once the version is agreed, before the handshake
+	// completes. Returning an error refuses the client, which receives the
+	// error (use an *RPCError to pick the code); it may try again.
+	// Optional.
+	Accept func(conn *Connection, params *InitializeParams) error
+}
+
+// ImplementationInfo names a client or server implementation.
+type ImplementationInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// InitializeParams are the params of the initialize method.
+type InitializeParams struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	ClientInfo      ImplementationInfo `json:"clientInfo"`
+	Capabilities    []string           `json:"capabilities,omitempty"`
+}
+
+// InitializeResult is the result of the initialize method.
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	ServerInfo      ImplementationInfo `json:"serverInfo"`
+	Capabilities    []string           `json:"capabilities"` // Negotiated
+}
+
+// handshakeState is what a connection agreed on in its handshake.
+type handshakeState struct {
+	version      string
+	client       ImplementationInfo
+	capabilities []string // Sorted
+}
+
+// Initialized reports whether the client has completed the handshake.
+// It is always false if ServerConfig.Handshake is not set.
+func (c *Connection) Initialized() bool {
+	return c.handshake.Load() != nil
+}
+
+// ProtocolVersion returns the protocol version agreed in the handshake.
+func (c *Connection) ProtocolVersion() string {
+	if h := c.handshake.Load(); h != nil {
+		return h.version
+	}
+	return ""
+}
+
+// ClientInfo returns the client implementation sent in the handshake.
+func (c *Connection) ClientInfo() ImplementationInfo {
+	if h := c.handshake.Load(); h != nil {
+		return h.client
+	}
+	return ImplementationInfo{}
+}
+
+// Capabilities returns the capabilities agreed in the handshake, sorted.
+func (c *Connection) Capabilities() []string {
+	h := c.handshake.Load()
+	if h == nil {
+		return nil
+	}
+	return append([]string(nil), h.capabilities...)
+}
+
+// HasCapability reports whether both client and server declared capability
+// in the handshake. Handlers use it before relying on an optional feature:
+//
+//	if conn := jsonrpc.ConnectionFromContext(ctx); conn.HasCapability("progress") {
+//	    conn.Notify("progress", p)
+//	}
+func (c *Connection) HasCapability(capability string) bool {
+	h := c.handshake.Load()
+	if h == nil {
+		return false
+	}
+	i := sort.SearchStrings(h.capabilities, capability)
+	return i < len(h.capabilities) && h.capabilities[i] == capability
+}
+
+// handshakeRequired reports whether the connection must complete the
+// handshake before other requests are served.
+func (c *Connection) handshakeRequired() bool {
+	return c.server != nil && c.server.config.Handshake != nil
+}
+
+// initializeHandler returns the handler of InitializeMethod.
+func initializeHandler(config *HandshakeConfig) Handler {
+	return HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		conn := ConnectionFromContext(ctx)
+		if conn == nil {
+			return nil, NewInternalError("no connection in context")
+		}
+		if conn.Initialized() {
+			return nil, NewInvalidRequestError("already initialized")
+		}
+
+		var p InitializeParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, NewInvalidParamsError(err.Error())
+		}
+		if len(config.ProtocolVersions) > 0 && !contains(config.ProtocolVersions, p.ProtocolVersion) {
+			return nil, NewInvalidParamsError(map[string]interface{}{
+				"message":           "unsupported protocol version",
+				"protocolVersion":   p.ProtocolVersion,
+				"supportedVersions": config.ProtocolVersions,
+			})
+		}
+		if config.Accept != nil {
+			if err := config.Accept(conn, &p); err != nil {
+				return nil, err
+			}
+		}
+
+		state := &handshakeState{
+			version:      p.ProtocolVersion,
+			client:       p.ClientInfo,
+			capabilities: []string{},
+		}
+		for _, capability := range p.Capabilities {
+			if contains(config.Capabilities, capability) && !contains(state.capabilities, capability) {
+				state.capabilities = append(state.capabilities, capability)
+			}
+		}
+		sort.Strings(state.capabilities)
+
+		if !conn.handshake.CompareAndSwap(nil, state) {
+			return nil, NewInvalidRequestError("already initialized")
+		}
+		if err := conn.SetState(StateInitialized); err != nil {
+			conn.logger.Debug("handshake did not change the connection state", "error", err)
+		}
+		conn.logger.Debug("client initialized", "client", p.ClientInfo.Name, "client_version", p.ClientInfo.Version, "protocol_version", p.ProtocolVersion)
+
+		return InitializeResult{
+			ProtocolVersion: state.version,
+			ServerInfo:      config.ServerInfo,
+			Capabilities:    state.capabilities,
+		}, nil
+	})
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
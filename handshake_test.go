@@ -0,0 +1,147 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// registerHandshakeMethods registers "echo", and an "initialize" handler that
+// the builtin shadows while the handshake is enabled.
+func registerHandshakeMethods(server *Server) {
+	server.RegisterFunc("echo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return string(params), nil
+	})
+	server.RegisterFunc(InitializeMethod, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "app initialize", nil
+	})
+}
+
+// call sends a request and returns the response or error response.
+func call(t *testing.T, c *Connection, message string) (*Response, *ErrorResponse) {
+	t.Helper()
+
+	reply, _ := c.handleMessage([]byte(message))
+	switch r := reply.(type) {
+	case *Response:
+		return r, nil
+	case *ErrorResponse:
+		return nil, r
+	default:
+		t.Fatalf("Reply = %#v, want a response", reply)
+		return nil, nil
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{
+		Handshake: &HandshakeConfig{
+			ProtocolVersions: []string{"2", "1"},
+			Capabilities:     []string{"progress", "cancel", "files"},
+			ServerInfo:       ImplementationInfo{Name: "testd", Version: "3.1.0"},
+		},
+	}, registerHandshakeMethods)
+
+	// Refused before the handshake
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":1}`); errResp == nil || errResp.Error.Code != ServerNotInitialized {
+		t.Fatalf("echo before initialize = %+v, want ServerNotInitialized", errResp)
+	}
+	if conn.Initialized() {
+		t.Fatal("Initialized() = true before the handshake")
+	}
+
+	resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1","clientInfo":{"name":"ext","version":"0.9"},"capabilities":["progress","files","telemetry","files"]},"id":2}`)
+	if errResp != nil {
+		t.Fatalf("initialize error = %+v", errResp.Error)
+	}
+
+	data, _ := json.Marshal(resp.Result)
+	var result InitializeResult
+	json.Unmarshal(data, &result)
+	if result.ProtocolVersion != "1" || result.ServerInfo.Name != "testd" {
+		t.Errorf("Result = %+v, want version 1 from testd", result)
+	}
+	if len(result.Capabilities) != 2 || result.Capabilities[0] != "files" || result.Capabilities[1] != "progress" {
+		t.Errorf("Result capabilities = %v, want [files progress]", result.Capabilities)
+	}
+
+	if !conn.Initialized() || conn.ProtocolVersion() != "1" || conn.ClientInfo().Name != "ext" {
+		t.Errorf("Connection state = %v %q %+v, want initialized with version 1 from ext",
+			conn.Initialized(), conn.ProtocolVersion(), conn.ClientInfo())
+	}
+	if !conn.HasCapability("progress") || conn.HasCapability("cancel") || conn.HasCapability("telemetry") {
+		t.Errorf("Capabilities() = %v, want [files progress]", conn.Capabilities())
+	}
+
+	// Served after the handshake
+	if resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":3}`); errResp != nil || resp.Result != "1" {
+		t.Errorf("echo after initialize = %+v, %+v", resp, errResp)
+	}
+
+	// Only once
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":4}`); errResp == nil || errResp.Error.Code != InvalidRequest {
+		t.Errorf("Second initialize = %+v, want InvalidRequest", errResp)
+	}
+}
+
+func TestHandshake_UnsupportedVersion(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{Handshake: &HandshakeConfig{ProtocolVersions: []string{"2"}}}, registerHandshakeMethods)
+
+	_, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":1}`)
+	if errResp == nil || errResp.Error.Code != InvalidParams {
+		t.Fatalf("initialize = %+v, want InvalidParams", errResp)
+	}
+	data, _ := errResp.Error.Data.(map[string]interface{})
+	if versions, _ := data["supportedVersions"].([]string); len(versions) != 1 || versions[0] != "2" {
+		t.Errorf("Error data = %v, want the supported versions", errResp.Error.Data)
+	}
+	if conn.Initialized() {
+		t.Error("Initialized() = true after a refused handshake")
+	}
+}
+
+func TestHandshake_Accept(t *testing.T) {
+	var accepted *InitializeParams
+	conn := newTestConnection(t, ServerConfig{
+		Handshake: &HandshakeConfig{
+			Accept: func(c *Connection, params *InitializeParams) error {
+				if params.ClientInfo.Version < "2" {
+					return NewError(InvalidRequest, "client too old", nil)
+				}
+				accepted = params
+				return nil
+			},
+		},
+	}, registerHandshakeMethods)
+
+	_, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"x","clientInfo":{"name":"ext","version":"1.0"}},"id":1}`)
+	if errResp == nil || errResp.Error.Message != "client too old" {
+		t.Fatalf("initialize = %+v, want the Accept error", errResp)
+	}
+	if conn.Initialized() {
+		t.Fatal("Initialized() = true after Accept refused the client")
+	}
+
+	// The client may try again; without ProtocolVersions any version is accepted
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"x","clientInfo":{"name":"ext","version":"2.0"}},"id":2}`); errResp != nil {
+		t.Fatalf("initialize error = %+v", errResp.Error)
+	}
+	if accepted == nil || conn.ProtocolVersion() != "x" {
+		t.Errorf("Accept params = %+v, version %q, want the accepted handshake", accepted, conn.ProtocolVersion())
+	}
+}
+
+func TestHandshake_Disabled(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{}, registerHandshakeMethods)
+
+	if resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":1}`); errResp != nil || resp.Result != "1" {
+		t.Errorf("echo without a handshake = %+v, %+v", resp, errResp)
+	}
+	// A registered "initialize" handler is an ordinary method
+	if resp, _ := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","id":2}`); resp == nil || resp.Result != "app initialize" {
+		t.Errorf("initialize without a handshake = %+v, want the registered handler", resp)
+	}
+	if conn.Initialized() || conn.HasCapability("progress") || conn.Capabilities() != nil {
+		t.Error("Connection without a handshake reports handshake state")
+	}
+}
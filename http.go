@@ -0,0 +1,283 @@
+package jsonrpcipc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// SessionHeader is the HTTP header that carries the server-sent events
+// session ID. POST requests that include it are dispatched on the session's
+// Connection, so handlers can send notifications back over the event stream.
+const SessionHeader = "X-Session-ID"
+
+// maxHTTPBodySize limits the size of a JSON-RPC request body sent over HTTP.
+const maxHTTPBodySize = 10 << 20
+
+// HTTPHandler serves a Server's handlers and middleware over HTTP.
+//
+// The handler accepts two kinds of requests:
+//   - POST: the body is a JSON-RPC request, notification or batch. The response
+//     (or array of responses) is returned as the JSON body, or 204 No Content
+//     if there is nothing to return.
+//   - GET: opens a server-sent events stream. The first event ("session")
+//     carries the session ID; every following event is a JSON-RPC notification
+//     sent with Server.Broadcast or Connection.Notify.
+//   - GET with "Upgrade: websocket": switches to the WebSocket transport
+//     (see WebSocketHandler).
+//
+// A POST without a session header runs on a new Connection that is closed
+// after the response, so nothing carries over between such requests: the
+// handshake (see InitializeMethod) and the authenticate method cannot
+// complete. Use a session for them, or send MetaAuthToken with every request.
+//
+// Example:
+//
+//	http.Handle("/rpc", server.HTTPHandler())
+//	log.Fatal(http.ListenAndServe("127.0.0.1:8080", nil))
+type HTTPHandler struct {
+	server   *Server
+	sessions sync.Map // map[string]*Connection
+}
+
+// HTTPHandler returns an http.Handler that serves the same handlers and
+// middleware as the IPC transport.
+func (s *Server) HTTPHandler() *HTTPHandler {
+	return s.http
+}
+
+// ServeHTTP implements http.Handler.
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		// Browsers send cross-origin "simple" POSTs without a preflight,
+		// so other web pages must be refused here
+		if !h.server.originAllowed(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		h.servePost(w, r)
+	case http.MethodGet:
+		if isWebSocketUpgrade(r) {
+			h.server.serveWebSocket(w, r)
+			return
+		}
+		if !h.server.originAllowed(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		h.serveEvents(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// servePost handles a JSON-RPC request, notification or batch sent as a POST body.
+func (h *HTTPHandler) servePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodySize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	h.server.metrics.received(len(body))
+
+	var conn *Connection
+	if id := r.Header.Get(SessionHeader); id != "" {
+		v, ok := h.sessions.Load(id)
+		if !ok {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		conn = v.(*Connection)
+	} else {
+		// Without a session, notifications sent by handlers have nowhere to go
+		conn = newCodecConnection(r.Context(), discardCodec{}, r.RemoteAddr, h.server.registry, h.server.middleware, h.server)
+		defer conn.Close()
+	}
+
+	conn.stats.bytesIn.Add(uint64(len(body)))
+
+	reply, _ := conn.handleMessage(body)
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	h.server.metrics.sent(len(data))
+	conn.stats.bytesOut.Add(uint64(len(data)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+
+	if conn.exited() {
+		// A handler ended the session (see StateExited)
+		conn.Close()
+	}
+}
+
+// serveEvents streams notifications to the client as server-sent events.
+// The stream is registered like any other connection, so it receives
+// broadcasts and triggers OnConnect/OnDisconnect.
+func (h *HTTPHandler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case <-h.server.shutdownCh:
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(SessionHeader, id)
+	w.WriteHeader(http.StatusOK)
+
+	codec := newSSECodec(w, flusher, r.Context())
+	session, _ := json.Marshal(map[string]string{"sessionId": id})
+	if err := codec.writeEvent("session", session); err != nil {
+		return
+	}
+
+	conn := newCodecConnection(context.Background(), codec, r.RemoteAddr, h.server.registry, h.server.middleware, h.server)
+	h.sessions.Store(id, conn)
+	defer h.sessions.Delete(id)
+
+	h.server.wg.Add(1)
+	defer h.server.wg.Done()
+
+	// Blocks until the client goes away or the connection is closed
+	h.server.serveConnection(conn)
+}
+
+// newSessionID returns a random, unguessable session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sseCodec is a write-only Codec that frames messages as server-sent events.
+// Reads block until the HTTP request ends or the codec is closed.
+type sseCodec struct {
+	w       io.Writer
+	flusher http.Flusher
+	done    <-chan struct{}
+
+	mu     sync.Mutex // Serializes writes and protects closed
+	closed bool
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+// newSSECodec creates an sseCodec writing to w until ctx is done.
+func newSSECodec(w io.Writer, flusher http.Flusher, ctx context.Context) *sseCodec {
+	return &sseCodec{
+		w:       w,
+		flusher: flusher,
+		done:    ctx.Done(),
+		closeCh: make(chan struct{}),
+	}
+}
+
+// writeEvent writes a single event. Multi-line data is split into one
+// "data:" field per line, as the event stream format requires.
+func (c *sseCodec) writeEvent(event string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("event stream is closed")
+	}
+
+	var buf bytes.Buffer
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := c.w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	c.flusher.Flush()
+	return nil
+}
+
+// ReadMessage blocks until the stream ends and then returns io.EOF.
+// Clients send requests with POST, never over the event stream.
+func (c *sseCodec) ReadMessage() ([]byte, error) {
+	select {
+	case <-c.done:
+	case <-c.closeCh:
+	}
+	return nil, io.EOF
+}
+
+// WriteMessage sends data as a "message" event.
+func (c *sseCodec) WriteMessage(data []byte) error {
+	return c.writeEvent("", data)
+}
+
+// ReadJSON blocks until the stream ends and then returns io.EOF.
+func (c *sseCodec) ReadJSON(v interface{}) error {
+	_, err := c.ReadMessage()
+	return err
+}
+
+// WriteJSON marshals v and sends it as a "message" event.
+func (c *sseCodec) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.WriteMessage(data)
+}
+
+// Close ends the stream. It waits for an in-progress write to finish, since
+// the http.ResponseWriter must not be used after the handler returns.
+func (c *sseCodec) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.closeOnce.Do(func() { close(c.closeCh) })
+	return nil
+}
+
+// discardCodec is the Codec for one-off HTTP requests without a session.
+// Writes succeed and are dropped; reads report EOF.
+type discardCodec struct{}
+
+func (discardCodec) ReadMessage() ([]byte, error)   { return nil, io.EOF }
+func (discardCodec) WriteMessage(data []byte) error { return nil }
+func (discardCodec) ReadJSON(v interface{}) error   { return io.EOF }
+func (discardCodec) WriteJSON(v interface{}) error  { return nil }
+func (discardCodec) Close() error                   { return nil }
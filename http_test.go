@@ -0,0 +1,339 @@
+package jsonrpcipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTPTestServer creates a Server with a few test methods and serves its
+// HTTPHandler from an httptest server.
+func newHTTPTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+
+	server, err := NewServer(ServerConfig{SocketPath: "test-http"})
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+
+	server.RegisterHandler("add", TypedHandler(func(ctx context.Context, p struct{ A, B int }) (int, error) {
+		return p.A + p.B, nil
+	}))
+	server.RegisterFunc("notifyMe", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		conn := ConnectionFromContext(ctx)
+		if err := conn.Notify("progress", map[string]int{"percentage": 50}); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	})
+
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Stop(ctx)
+		httpServer.Close()
+	})
+
+	return server, httpServer
+}
+
+// postJSON posts body to url and returns the response.
+func postJSON(t *testing.T, url, body string, header http.Header) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHTTPHandler_Request(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	resp := postJSON(t, httpServer.URL, `{"jsonrpc":"2.0","method":"add","params":{"A":2,"B":3},"id":1}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var result Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result.Result != float64(5) {
+		t.Errorf("Result = %v, want 5", result.Result)
+	}
+	if !compareIDs(result.ID, 1) {
+		t.Errorf("ID = %v, want 1", result.ID)
+	}
+}
+
+func TestHTTPHandler_Batch(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	body := `[
+		{"jsonrpc":"2.0","method":"add","params":{"A":1,"B":1},"id":1},
+		{"jsonrpc":"2.0","method":"add","params":{"A":2,"B":2}},
+		{"jsonrpc":"2.0","method":"missing","id":2}
+	]`
+	resp := postJSON(t, httpServer.URL, body, nil)
+
+	var replies []Message
+	if err := json.NewDecoder(resp.Body).Decode(&replies); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	// The notification gets no reply
+	if len(replies) != 2 {
+		t.Fatalf("Batch replies = %d, want 2", len(replies))
+	}
+	if string(replies[0].Result) != "2" {
+		t.Errorf("First result = %s, want 2", replies[0].Result)
+	}
+	if replies[1].Error == nil || replies[1].Error.Code != MethodNotFound {
+		t.Errorf("Second reply error = %v, want MethodNotFound", replies[1].Error)
+	}
+}
+
+func TestHTTPHandler_NotificationOnly(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	resp := postJSON(t, httpServer.URL, `{"jsonrpc":"2.0","method":"add","params":{"A":1,"B":1}}`, nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Status = %d, want 204", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_ParseError(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	resp := postJSON(t, httpServer.URL, `{invalid json}`, nil)
+
+	var errResp ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if errResp.Error == nil || errResp.Error.Code != ParseError {
+		t.Errorf("Error = %v, want ParseError", errResp.Error)
+	}
+}
+
+func TestHTTPHandler_MethodNotAllowed(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodDelete, httpServer.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_CrossOriginPost(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+	body := `{"jsonrpc":"2.0","method":"add","params":{"A":2,"B":3},"id":1}`
+
+	// A form or fetch from another page sends text/plain without a preflight
+	resp := postJSON(t, httpServer.URL, body, http.Header{
+		"Origin":       {"http://evil.example"},
+		"Content-Type": {"text/plain"},
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cross-origin POST status = %d, want 403", resp.StatusCode)
+	}
+
+	resp = postJSON(t, httpServer.URL, body, http.Header{"Origin": {httpServer.URL}})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Same-origin POST status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_UnknownSession(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	resp := postJSON(t, httpServer.URL, `{"jsonrpc":"2.0","method":"add","id":1}`,
+		http.Header{SessionHeader: []string{"does-not-exist"}})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status = %d, want 404", resp.StatusCode)
+	}
+}
+
+// sseEvent is a parsed server-sent event.
+type sseEvent struct {
+	event string
+	data  string
+}
+
+// readEvents parses server-sent events from the stream into a channel.
+func readEvents(resp *http.Response) <-chan sseEvent {
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				events <- ev
+				ev = sseEvent{}
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data += strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+// nextEvent waits for the next event on the stream.
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("Event stream closed")
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return sseEvent{}
+}
+
+// openEventStream opens the SSE endpoint and returns the session ID and events.
+func openEventStream(t *testing.T, server *Server, url string) (string, <-chan sseEvent) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	events := readEvents(resp)
+	ev := nextEvent(t, events)
+	if ev.event != "session" {
+		t.Fatalf("First event = %q, want session", ev.event)
+	}
+
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.Unmarshal([]byte(ev.data), &session); err != nil {
+		t.Fatalf("Session event data %q: %v", ev.data, err)
+	}
+	if session.SessionID != resp.Header.Get(SessionHeader) {
+		t.Errorf("Session ID %q does not match header %q", session.SessionID, resp.Header.Get(SessionHeader))
+	}
+
+	// Wait until the stream is registered for broadcasts
+	deadline := time.Now().Add(time.Second)
+	for server.ConnectionCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return session.SessionID, events
+}
+
+func TestHTTPHandler_EventStream_Broadcast(t *testing.T) {
+	server, httpServer := newHTTPTestServer(t)
+	_, events := openEventStream(t, server, httpServer.URL)
+
+	if count := server.Broadcast("build.done", map[string]string{"status": "ok"}); count != 1 {
+		t.Errorf("Broadcast() count = %d, want 1", count)
+	}
+
+	ev := nextEvent(t, events)
+	var notif Notification
+	if err := json.Unmarshal([]byte(ev.data), &notif); err != nil {
+		t.Fatalf("Event data %q: %v", ev.data, err)
+	}
+	if notif.Method != "build.done" {
+		t.Errorf("Notification method = %q, want build.done", notif.Method)
+	}
+}
+
+func TestHTTPHandler_EventStream_SessionNotify(t *testing.T) {
+	server, httpServer := newHTTPTestServer(t)
+	sessionID, events := openEventStream(t, server, httpServer.URL)
+
+	resp := postJSON(t, httpServer.URL, `{"jsonrpc":"2.0","method":"notifyMe","id":7}`,
+		http.Header{SessionHeader: []string{sessionID}})
+
+	var result Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result.Result != "ok" {
+		t.Errorf("Result = %v, want ok", result.Result)
+	}
+
+	ev := nextEvent(t, events)
+	var notif Notification
+	if err := json.Unmarshal([]byte(ev.data), &notif); err != nil {
+		t.Fatalf("Event data %q: %v", ev.data, err)
+	}
+	if notif.Method != "progress" {
+		t.Errorf("Notification method = %q, want progress", notif.Method)
+	}
+}
+
+func TestHTTPHandler_EventStream_Disconnect(t *testing.T) {
+	server, httpServer := newHTTPTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for server.ConnectionCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if server.ConnectionCount() != 1 {
+		t.Fatalf("ConnectionCount() = %d, want 1", server.ConnectionCount())
+	}
+
+	// Closing the stream unregisters the connection
+	cancel()
+
+	deadline = time.Now().Add(time.Second)
+	for server.ConnectionCount() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if server.ConnectionCount() != 0 {
+		t.Errorf("ConnectionCount() after disconnect = %d, want 0", server.ConnectionCount())
+	}
+}
@@ -0,0 +1,148 @@
+package jsonrpcipc
+
+import "fmt"
+
+// ConnectionState is a stage in a connection's lifecycle. States only move
+// forward: uninitialized, initialized, shutting down, exited.
+type ConnectionState int32
+
+const (
+	// StateUninitialized is the state of a new connection.
+	StateUninitialized ConnectionState = iota
+
+	// StateInitialized follows a completed handshake (see HandshakeConfig),
+	// or a handler calling SetState.
+	StateInitialized
+
+	// StateShuttingDown is entered through SetState, or for every connection
+	// when the server stops with a LifecycleConfig set.
+	StateShuttingDown
+
+	// StateExited ends the connection: it closes once the reply to the
+	// current request has been sent.
+	StateExited
+)
+
+// String returns the state name.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateUninitialized:
+		return "uninitialized"
+	case StateInitialized:
+		return "initialized"
+	case StateShuttingDown:
+		return "shutting-down"
+	case StateExited:
+		return "exited"
+	default:
+		return fmt.Sprintf("ConnectionState(%d)", int32(s))
+	}
+}
+
+// LifecycleConfig restricts the methods a connection may call in each state,
+// for LSP-like protocols:
+//
+//	Lifecycle: &jsonrpc.LifecycleConfig{
+//	    Methods: map[jsonrpc.ConnectionState][]string{
+//	        jsonrpc.StateUninitialized: {"initialize"},
+//	        jsonrpc.StateShuttingDown:  {"exit"},
+//	    },
+//	}
+//
+// Handlers move connections between states with Connection.SetState.
+type LifecycleConfig struct {
+	// Methods maps a state to the methods available in it, as names or
+	// patterns ("*" within a dot-separated segment, "**" across segments).
+	// States without an entry allow every method.
+	Methods map[ConnectionState][]string
+
+	// RefusedError returns the error for a request refused in state. If nil,
+	// requests are refused with ServerNotInitialized while uninitialized and
+	// with InvalidRequest otherwise.
+	// Optional.
+	RefusedError func(state ConnectionState, method string) *RPCError
+
+	// OnTransition is called after a connection changes state.
+	// Optional.
+	OnTransition func(conn *Connection, from, to ConnectionState)
+}
+
+// allows reports whether method is available in state.
+func (lc *LifecycleConfig) allows(state ConnectionState, method string) bool {
+	patterns, ok := lc.Methods[state]
+	return !ok || matchAny(patterns, method)
+}
+
+// refusedError returns the error for a request refused in state.
+func (lc *LifecycleConfig) refusedError(state ConnectionState, method string) *RPCError {
+	if lc.RefusedError != nil {
+		if err := lc.RefusedError(state, method); err != nil {
+			return err
+		}
+	}
+	data := map[string]string{"method": method, "state": state.String()}
+	if state == StateUninitialized {
+		return NewServerNotInitializedError(data)
+	}
+	return NewInvalidRequestError(data)
+}
+
+// State returns the connection's lifecycle state.
+func (c *Connection) State() ConnectionState {
+	return ConnectionState(c.state.Load())
+}
+
+// SetState moves the connection to a later lifecycle state. Moving to the
+// current state does nothing; moving back is an error.
+//
+// Entering StateExited closes the connection once the reply to the current
+// request has been sent, or right away when no request is being handled.
+//
+// Example:
+//
+//	server.RegisterFunc("shutdown", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+//	    return nil, jsonrpc.ConnectionFromContext(ctx).SetState(jsonrpc.StateShuttingDown)
+//	})
+//
+// Thread-safety: This method is safe to call concurrently.
+func (c *Connection) SetState(to ConnectionState) error {
+	if to < StateUninitialized || to > StateExited {
+		return fmt.Errorf("invalid connection state %v", to)
+	}
+
+	var from ConnectionState
+	for {
+		from = c.State()
+		if to == from {
+			return nil
+		}
+		if to < from {
+			return fmt.Errorf("cannot move connection from %v back to %v", from, to)
+		}
+		if c.state.CompareAndSwap(int32(from), int32(to)) {
+			break
+		}
+	}
+
+	c.logger.Debug("connection state changed", "from", from.String(), "to", to.String())
+	if lc := c.lifecycle(); lc != nil && lc.OnTransition != nil {
+		lc.OnTransition(c, from, to)
+	}
+	if to == StateExited && c.stats.inFlight.Load() == 0 {
+		go c.closeAfterFlush()
+	}
+	return nil
+}
+
+// lifecycle returns the server's lifecycle configuration, or nil.
+func (c *Connection) lifecycle() *LifecycleConfig {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.config.Lifecycle
+}
+
+// exited reports whether the connection should close after its current reply.
+func (c *Connection) exited() bool {
+	return c.State() == StateExited
+}
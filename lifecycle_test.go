@@ -0,0 +1,267 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// registerLifecycleMethods registers "echo", "shutdown" and "exit".
+func registerLifecycleMethods(server *Server) {
+	server.RegisterFunc("echo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return string(params), nil
+	})
+	server.RegisterFunc("shutdown", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, ConnectionFromContext(ctx).SetState(StateShuttingDown)
+	})
+	server.RegisterFunc("exit", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, ConnectionFromContext(ctx).SetState(StateExited)
+	})
+}
+
+func TestConnectionState_String(t *testing.T) {
+	tests := map[ConnectionState]string{
+		StateUninitialized: "uninitialized",
+		StateInitialized:   "initialized",
+		StateShuttingDown:  "shutting-down",
+		StateExited:        "exited",
+		ConnectionState(9): "ConnectionState(9)",
+	}
+	for state, want := range tests {
+		if got := state.String(); got != want {
+			t.Errorf("ConnectionState(%d).String() = %q, want %q", int32(state), got, want)
+		}
+	}
+}
+
+func TestLifecycle_Methods(t *testing.T) {
+	var mu sync.Mutex
+	var transitions []ConnectionState
+	conn := newTestConnection(t, ServerConfig{
+		Handshake: &HandshakeConfig{},
+		Lifecycle: &LifecycleConfig{
+			Methods: map[ConnectionState][]string{
+				StateUninitialized: {InitializeMethod},
+				StateShuttingDown:  {"exit"},
+			},
+			OnTransition: func(c *Connection, from, to ConnectionState) {
+				mu.Lock()
+				transitions = append(transitions, to)
+				mu.Unlock()
+			},
+		},
+	}, registerLifecycleMethods)
+
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":1}`); errResp == nil || errResp.Error.Code != ServerNotInitialized {
+		t.Fatalf("echo while uninitialized = %+v, want ServerNotInitialized", errResp)
+	}
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":2}`); errResp != nil {
+		t.Fatalf("initialize error = %+v", errResp.Error)
+	}
+	if got := conn.State(); got != StateInitialized {
+		t.Fatalf("State() after initialize = %v, want initialized", got)
+	}
+	if resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":3}`); errResp != nil || resp.Result != "1" {
+		t.Fatalf("echo while initialized = %+v, %+v", resp, errResp)
+	}
+
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"shutdown","id":4}`); errResp != nil {
+		t.Fatalf("shutdown error = %+v", errResp.Error)
+	}
+	_, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":5}`)
+	if errResp == nil || errResp.Error.Code != InvalidRequest {
+		t.Fatalf("echo while shutting down = %+v, want InvalidRequest", errResp)
+	}
+	if data, _ := errResp.Error.Data.(map[string]string); data["state"] != "shutting-down" || data["method"] != "echo" {
+		t.Errorf("Error data = %v, want the method and state", errResp.Error.Data)
+	}
+
+	if _, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"exit","id":6}`); errResp != nil {
+		t.Fatalf("exit error = %+v", errResp.Error)
+	}
+	if got := conn.State(); got != StateExited {
+		t.Errorf("State() after exit = %v, want exited", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []ConnectionState{StateInitialized, StateShuttingDown, StateExited}
+	if len(transitions) != len(want) {
+		t.Fatalf("Transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Transitions = %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestLifecycleConfig_Allows(t *testing.T) {
+	lc := &LifecycleConfig{
+		Methods: map[ConnectionState][]string{
+			StateUninitialized: {InitializeMethod, "auth.*"},
+			StateShuttingDown:  {},
+		},
+	}
+
+	tests := []struct {
+		state  ConnectionState
+		method string
+		want   bool
+	}{
+		{StateUninitialized, InitializeMethod, true},
+		{StateUninitialized, "auth.login", true},
+		{StateUninitialized, "auth.token.refresh", false},
+		{StateUninitialized, "echo", false},
+		{StateInitialized, "echo", true}, // No entry
+		{StateShuttingDown, "exit", false},
+	}
+	for _, tt := range tests {
+		if got := lc.allows(tt.state, tt.method); got != tt.want {
+			t.Errorf("allows(%v, %q) = %v, want %v", tt.state, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestLifecycle_RefusedError(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{
+		Handshake: &HandshakeConfig{},
+		Lifecycle: &LifecycleConfig{
+			Methods: map[ConnectionState][]string{StateUninitialized: {}},
+			RefusedError: func(state ConnectionState, method string) *RPCError {
+				return NewError(-32099, "not now", state.String())
+			},
+		},
+	}, registerLifecycleMethods)
+
+	_, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":1}`)
+	if errResp == nil || errResp.Error.Code != -32099 || errResp.Error.Data != "uninitialized" {
+		t.Fatalf("initialize = %+v, want the custom error", errResp)
+	}
+}
+
+func TestConnection_SetState(t *testing.T) {
+	conn := newTestConnection(t, ServerConfig{Handshake: &HandshakeConfig{}}, registerLifecycleMethods)
+
+	if err := conn.SetState(StateShuttingDown); err != nil {
+		t.Fatalf("SetState(shutting-down) error = %v", err)
+	}
+	if err := conn.SetState(StateShuttingDown); err != nil {
+		t.Errorf("SetState() to the current state error = %v", err)
+	}
+	if err := conn.SetState(StateInitialized); err == nil {
+		t.Error("SetState() back to initialized: expected error")
+	}
+	if err := conn.SetState(ConnectionState(7)); err == nil {
+		t.Error("SetState() to an unknown state: expected error")
+	}
+	if got := conn.State(); got != StateShuttingDown {
+		t.Errorf("State() = %v, want shutting-down", got)
+	}
+
+	// Without a LifecycleConfig, states are tracked but do not gate methods
+	if resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"1"},"id":1}`); errResp != nil {
+		t.Fatalf("initialize = %+v, %+v", resp, errResp)
+	}
+	if resp, errResp := call(t, conn, `{"jsonrpc":"2.0","method":"echo","params":1,"id":2}`); errResp != nil {
+		t.Errorf("echo while shutting down without a lifecycle = %+v, %+v", resp, errResp)
+	}
+}
+
+func TestLifecycle_ExitClosesConnection(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+	registerLifecycleMethods(server)
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+
+	codec := NewCodec(conn)
+	if err := codec.WriteJSON(&Request{JSONRPC: "2.0", Method: "exit", ID: 1}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var resp Response
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v, want the exit reply", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := codec.ReadJSON(&resp); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadJSON() after exit error = %v, want EOF", err)
+	}
+	waitForConnections(t, server, 0)
+}
+
+func TestLifecycle_SetStateExitedClosesIdleConnection(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+
+	infos := waitForConnections(t, server, 1)
+	if err := server.Connection(infos[0].ID).SetState(StateExited); err != nil {
+		t.Fatalf("SetState(exited) error = %v", err)
+	}
+	waitForConnections(t, server, 0)
+}
+
+func TestLifecycle_StopShutsDownConnections(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{
+		SocketPath: "tcp://127.0.0.1:0",
+		Lifecycle: &LifecycleConfig{
+			Methods: map[ConnectionState][]string{StateShuttingDown: {"exit"}},
+		},
+	})
+	registerLifecycleMethods(server)
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+	if _, err := callAdd(t, conn); err != nil {
+		t.Fatalf("callAdd() error = %v", err)
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop(context.Background()) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		infos := server.Connections()
+		if len(infos) == 1 && infos[0].State == StateShuttingDown {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Connections() = %+v, want one shutting down", infos)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	codec := NewCodec(conn)
+	codec.WriteJSON(&Request{JSONRPC: "2.0", Method: "add", Params: json.RawMessage(`{"a":1,"b":2}`), ID: 2})
+	var errResp ErrorResponse
+	if err := codec.ReadJSON(&errResp); err != nil || errResp.Error == nil || errResp.Error.Code != InvalidRequest {
+		t.Fatalf("add while stopping = %+v, %v, want InvalidRequest", errResp.Error, err)
+	}
+
+	// The client exits and Stop completes without forcing the connection closed
+	codec.WriteJSON(&Request{JSONRPC: "2.0", Method: "exit", ID: 3})
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stop() did not return after the client exited")
+	}
+}
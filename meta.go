@@ -0,0 +1,104 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"sync"
+)
+
+// Envelope metadata (the "meta" member).
+//
+// Requests may carry a "meta" object next to their params for data about the
+// call itself, such as an auth token, locale, client version or trace
+// context. Handlers read it with MetaFromContext. Responses carry a "meta"
+// object when the handler or a middleware sets one with SetResponseMeta,
+// for example for timing or deprecation warnings:
+//
+//	{"jsonrpc":"2.0","method":"search","params":{"q":"go"},"id":1,"meta":{"locale":"de"}}
+//	{"jsonrpc":"2.0","result":[],"id":1,"meta":{"deprecated":"use search2"}}
+//
+// With ServerConfig.StrictSpec, requests carrying "meta" are rejected and
+// the server never sends it.
+
+// MetaFromContext retrieves the "meta" member of the current request.
+// Returns nil if the request carried none.
+func MetaFromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(contextKeyMeta).(Meta)
+	return meta
+}
+
+// WithMeta adds request metadata to the context.
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, contextKeyMeta, meta)
+}
+
+// SetResponseMeta sets a key in the "meta" member of the response to the
+// current request. It may be called by the handler or by middleware, before
+// or after the handler runs, and applies to error responses too.
+// Outside a request (or with ServerConfig.StrictSpec) it does nothing.
+//
+// Example:
+//
+//	func timingMiddleware(next jsonrpc.Handler) jsonrpc.Handler {
+//	    return jsonrpc.HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+//	        start := time.Now()
+//	        result, err := next.Handle(ctx, params)
+//	        jsonrpc.SetResponseMeta(ctx, "durationMs", time.Since(start).Milliseconds())
+//	        return result, err
+//	    })
+//	}
+//
+// Thread-safety: This function is safe to call concurrently.
+func SetResponseMeta(ctx context.Context, key string, value interface{}) {
+	if rm, ok := ctx.Value(contextKeyResponseMeta).(*responseMeta); ok {
+		rm.set(key, value)
+	}
+}
+
+// responseMeta collects the metadata of one response while its request is
+// being handled.
+type responseMeta struct {
+	mu   sync.Mutex
+	meta Meta
+}
+
+// set stores a key.
+func (rm *responseMeta) set(key string, value interface{}) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.meta == nil {
+		rm.meta = make(Meta)
+	}
+	rm.meta[key] = value
+}
+
+// get returns a copy of the collected metadata, or nil if none was set.
+func (rm *responseMeta) get() Meta {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if len(rm.meta) == 0 {
+		return nil
+	}
+	meta := make(Meta, len(rm.meta))
+	for k, v := range rm.meta {
+		meta[k] = v
+	}
+	return meta
+}
+
+// withReplyMeta sets meta on a reply built by dispatch.
+func withReplyMeta(reply interface{}, meta Meta) interface{} {
+	if meta == nil {
+		return reply
+	}
+	switch r := reply.(type) {
+	case *Response:
+		r.Meta = meta
+	case *ErrorResponse:
+		r.Meta = meta
+	case *fileReply:
+		r.response.Meta = meta
+	}
+	return reply
+}
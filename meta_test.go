@@ -0,0 +1,111 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMetaFromContext(t *testing.T) {
+	if meta := MetaFromContext(context.Background()); meta != nil {
+		t.Errorf("MetaFromContext() without meta = %v, want nil", meta)
+	}
+
+	ctx := WithMeta(context.Background(), Meta{"locale": "de"})
+	if meta := MetaFromContext(ctx); meta["locale"] != "de" {
+		t.Errorf("MetaFromContext() = %v, want locale de", meta)
+	}
+
+	// Outside a request there is no response to attach meta to
+	SetResponseMeta(context.Background(), "key", "value")
+}
+
+// registerMetaMethods registers handlers that echo the request meta and set
+// response meta.
+func registerMetaMethods(server *Server) {
+	server.RegisterFunc("echoMeta", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		SetResponseMeta(ctx, "served", true)
+		return MetaFromContext(ctx), nil
+	})
+	server.RegisterFunc("fail", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		SetResponseMeta(ctx, "deprecated", "use fail2")
+		return nil, errors.New("failed")
+	})
+}
+
+func TestConnection_RequestMeta(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{}, registerMetaMethods)
+
+	reply, err := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"echoMeta","id":1,"meta":{"locale":"de"}}`))
+	if err != nil {
+		t.Fatalf("handleMessage() error = %v", err)
+	}
+
+	resp, ok := reply.(*Response)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *Response", reply)
+	}
+	if got, _ := resp.Result.(Meta); got["locale"] != "de" {
+		t.Errorf("Handler saw meta %v, want locale de", resp.Result)
+	}
+	if resp.Meta["served"] != true {
+		t.Errorf("Response meta = %v, want served", resp.Meta)
+	}
+
+	data, _ := json.Marshal(resp)
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	if _, ok := decoded["meta"]; !ok {
+		t.Errorf("Encoded response %s has no meta member", data)
+	}
+}
+
+func TestConnection_ErrorResponseMeta(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{}, registerMetaMethods)
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"fail","id":1}`))
+	resp, ok := reply.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *ErrorResponse", reply)
+	}
+	if resp.Meta["deprecated"] != "use fail2" {
+		t.Errorf("Error response meta = %v, want deprecated", resp.Meta)
+	}
+}
+
+func TestConnection_ResponseWithoutMeta(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{}, registerMetaMethods)
+	connection.registry.RegisterFunc("plain", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "ok", nil
+	})
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"plain","id":1}`))
+	data, _ := json.Marshal(reply)
+	if string(data) != `{"jsonrpc":"2.0","result":"ok","id":1}` {
+		t.Errorf("Response = %s, want no meta member", data)
+	}
+}
+
+func TestConnection_StrictSpecRejectsMeta(t *testing.T) {
+	connection := newTestConnection(t, ServerConfig{StrictSpec: true}, registerMetaMethods)
+
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"echoMeta","id":1,"meta":{"locale":"de"}}`))
+	errResp, ok := reply.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *ErrorResponse", reply)
+	}
+	if errResp.Error.Code != InvalidRequest {
+		t.Errorf("Error code = %d, want %d", errResp.Error.Code, InvalidRequest)
+	}
+
+	// Without meta the request is served, and no meta is sent back
+	reply, _ = connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"echoMeta","id":2}`))
+	resp, ok := reply.(*Response)
+	if !ok {
+		t.Fatalf("Reply = %#v, want *Response", reply)
+	}
+	if resp.Meta != nil {
+		t.Errorf("Response meta = %v, want none in strict mode", resp.Meta)
+	}
+}
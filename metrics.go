@@ -0,0 +1,420 @@
+package jsonrpcipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsMethod is the built-in JSON-RPC method that returns the server's
+// metrics in Prometheus text format. Registering a handler with the same
+// name replaces it.
+const MetricsMethod = "$/metrics"
+
+// unknownMethodLabel is the method label for requests to unregistered
+// methods, so clients cannot create unbounded label values.
+const unknownMethodLabel = "(unknown)"
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration
+// histogram. IPC calls are fast, so the buckets start at 100µs.
+var latencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics records request, connection and traffic statistics for a Server.
+// It is collected automatically; use Server.Metrics to read it.
+//
+// Metrics implements http.Handler, serving the Prometheus text exposition
+// format, so it can be mounted next to the application's other handlers:
+//
+//	http.Handle("/metrics", server.Metrics())
+//
+// The same text is returned by the built-in "$/metrics" JSON-RPC method.
+//
+// Thread-safety: Metrics is safe for concurrent use.
+type Metrics struct {
+	mu      sync.Mutex // Protects methods
+	methods map[string]*methodMetrics
+
+	inFlight            atomic.Int64
+	connections         atomic.Int64
+	connectionsTotal    atomic.Uint64
+	bytesIn             atomic.Uint64
+	bytesOut            atomic.Uint64
+	broadcasts          atomic.Uint64
+	broadcastRecipients atomic.Uint64
+	droppedMessages     atomic.Uint64
+}
+
+// methodMetrics holds the request statistics of a single method.
+type methodMetrics struct {
+	requests uint64
+	errors   map[int]uint64 // By error code
+	buckets  []uint64       // Cumulative counts, parallel to latencyBuckets
+	sum      float64        // Total duration in seconds
+
+	abandoned      int64  // Handlers still running after their timeout
+	abandonedTotal uint64 // Handlers abandoned since the server started
+}
+
+// newMetrics creates an empty Metrics.
+func newMetrics() *Metrics {
+	return &Metrics{methods: make(map[string]*methodMetrics)}
+}
+
+// requestStarted marks a request as in flight.
+func (m *Metrics) requestStarted() {
+	if m == nil {
+		return
+	}
+	m.inFlight.Add(1)
+}
+
+// requestFinished records a completed request. code is 0 on success.
+func (m *Metrics) requestFinished(method string, duration time.Duration, code int) {
+	if m == nil {
+		return
+	}
+	m.inFlight.Add(-1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mm := m.method(method)
+	mm.requests++
+	if code != 0 {
+		mm.errors[code]++
+	}
+	seconds := duration.Seconds()
+	mm.sum += seconds
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			mm.buckets[i]++
+		}
+	}
+}
+
+// method returns the statistics of a method, creating them if needed.
+// The caller must hold mu.
+func (m *Metrics) method(name string) *methodMetrics {
+	mm, ok := m.methods[name]
+	if !ok {
+		mm = &methodMetrics{
+			errors:  make(map[int]uint64),
+			buckets: make([]uint64, len(latencyBuckets)),
+		}
+		m.methods[name] = mm
+	}
+	return mm
+}
+
+// handlerAbandoned records a handler left running after its timeout.
+func (m *Metrics) handlerAbandoned(method string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mm := m.method(method)
+	mm.abandoned++
+	mm.abandonedTotal++
+}
+
+// abandonedFinished records that an abandoned handler returned.
+func (m *Metrics) abandonedFinished(method string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.method(method).abandoned--
+}
+
+// connectionOpened records a new connection.
+func (m *Metrics) connectionOpened() {
+	if m == nil {
+		return
+	}
+	m.connections.Add(1)
+	m.connectionsTotal.Add(1)
+}
+
+// connectionClosed records a closed connection.
+func (m *Metrics) connectionClosed() {
+	if m == nil {
+		return
+	}
+	m.connections.Add(-1)
+}
+
+// received records bytes read from clients.
+func (m *Metrics) received(n int) {
+	if m == nil {
+		return
+	}
+	m.bytesIn.Add(uint64(n))
+}
+
+// sent records bytes written to clients.
+func (m *Metrics) sent(n int) {
+	if m == nil {
+		return
+	}
+	m.bytesOut.Add(uint64(n))
+}
+
+// broadcast records a broadcast delivered to recipients connections.
+func (m *Metrics) broadcast(recipients int) {
+	if m == nil {
+		return
+	}
+	m.broadcasts.Add(1)
+	m.broadcastRecipients.Add(uint64(recipients))
+}
+
+// dropped records an outbound message discarded by a full write queue.
+func (m *Metrics) dropped() {
+	if m == nil {
+		return
+	}
+	m.droppedMessages.Add(1)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	m.mu.Lock()
+	names := make([]string, 0, len(m.methods))
+	for name := range m.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(bw, "jsonrpc_requests_total", "counter", "Requests handled, by method.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "jsonrpc_requests_total{method=%s} %d\n", labelValue(name), m.methods[name].requests)
+	}
+
+	writeHeader(bw, "jsonrpc_request_errors_total", "counter", "Requests that returned an error, by method and JSON-RPC error code.")
+	for _, name := range names {
+		mm := m.methods[name]
+		codes := make([]int, 0, len(mm.errors))
+		for code := range mm.errors {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(bw, "jsonrpc_request_errors_total{method=%s,code=\"%d\"} %d\n", labelValue(name), code, mm.errors[code])
+		}
+	}
+
+	writeHeader(bw, "jsonrpc_request_duration_seconds", "histogram", "Request handling latency, by method.")
+	for _, name := range names {
+		mm := m.methods[name]
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(bw, "jsonrpc_request_duration_seconds_bucket{method=%s,le=\"%s\"} %d\n", labelValue(name), formatFloat(bound), mm.buckets[i])
+		}
+		fmt.Fprintf(bw, "jsonrpc_request_duration_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", labelValue(name), mm.requests)
+		fmt.Fprintf(bw, "jsonrpc_request_duration_seconds_sum{method=%s} %s\n", labelValue(name), formatFloat(mm.sum))
+		fmt.Fprintf(bw, "jsonrpc_request_duration_seconds_count{method=%s} %d\n", labelValue(name), mm.requests)
+	}
+
+	writeHeader(bw, "jsonrpc_abandoned_handlers", "gauge", "Handlers still running after their timeout, by method.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "jsonrpc_abandoned_handlers{method=%s} %d\n", labelValue(name), m.methods[name].abandoned)
+	}
+
+	writeHeader(bw, "jsonrpc_abandoned_handlers_total", "counter", "Handlers abandoned after their timeout, by method.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "jsonrpc_abandoned_handlers_total{method=%s} %d\n", labelValue(name), m.methods[name].abandonedTotal)
+	}
+	m.mu.Unlock()
+
+	writeHeader(bw, "jsonrpc_requests_in_flight", "gauge", "Requests currently being handled.")
+	fmt.Fprintf(bw, "jsonrpc_requests_in_flight %d\n", m.inFlight.Load())
+
+	writeHeader(bw, "jsonrpc_connections", "gauge", "Open client connections.")
+	fmt.Fprintf(bw, "jsonrpc_connections %d\n", m.connections.Load())
+
+	writeHeader(bw, "jsonrpc_connections_total", "counter", "Client connections accepted.")
+	fmt.Fprintf(bw, "jsonrpc_connections_total %d\n", m.connectionsTotal.Load())
+
+	writeHeader(bw, "jsonrpc_received_bytes_total", "counter", "Bytes of JSON-RPC messages received from clients.")
+	fmt.Fprintf(bw, "jsonrpc_received_bytes_total %d\n", m.bytesIn.Load())
+
+	writeHeader(bw, "jsonrpc_sent_bytes_total", "counter", "Bytes of JSON-RPC messages sent to clients.")
+	fmt.Fprintf(bw, "jsonrpc_sent_bytes_total %d\n", m.bytesOut.Load())
+
+	writeHeader(bw, "jsonrpc_broadcasts_total", "counter", "Broadcasts sent.")
+	fmt.Fprintf(bw, "jsonrpc_broadcasts_total %d\n", m.broadcasts.Load())
+
+	writeHeader(bw, "jsonrpc_broadcast_recipients_total", "counter", "Notifications delivered by broadcasts.")
+	fmt.Fprintf(bw, "jsonrpc_broadcast_recipients_total %d\n", m.broadcastRecipients.Load())
+
+	writeHeader(bw, "jsonrpc_dropped_messages_total", "counter", "Outbound messages dropped by full write queues.")
+	fmt.Fprintf(bw, "jsonrpc_dropped_messages_total %d\n", m.droppedMessages.Load())
+
+	return bw.Flush()
+}
+
+// String returns the metrics in the Prometheus text exposition format.
+func (m *Metrics) String() string {
+	var sb strings.Builder
+	m.WritePrometheus(&sb)
+	return sb.String()
+}
+
+// ServeHTTP implements http.Handler.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family.
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelValue quotes and escapes a label value.
+func labelValue(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+// formatFloat formats a sample value or bucket bound.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricsHandler returns the handler of the built-in "$/metrics" method.
+func metricsHandler(m *Metrics) Handler {
+	return HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return m.String(), nil
+	})
+}
+
+// connectionStats are the traffic counters of one connection.
+type connectionStats struct {
+	inFlight atomic.Int64
+	bytesIn  atomic.Uint64
+	bytesOut atomic.Uint64
+}
+
+// meteredCodec counts the bytes of the messages passing through a Codec,
+// for the connection and for the server metrics (if set).
+// It forwards file passing to the wrapped codec when that supports it.
+type meteredCodec struct {
+	Codec
+	stats   *connectionStats
+	metrics *Metrics
+}
+
+// received records an incoming message of n bytes.
+func (c *meteredCodec) received(n int) {
+	c.stats.bytesIn.Add(uint64(n))
+	c.metrics.received(n)
+}
+
+// sent records an outgoing message of n bytes.
+func (c *meteredCodec) sent(n int) {
+	c.stats.bytesOut.Add(uint64(n))
+	c.metrics.sent(n)
+}
+
+// ReadMessage reads the next message and records its size.
+func (c *meteredCodec) ReadMessage() ([]byte, error) {
+	data, err := c.Codec.ReadMessage()
+	if err == nil {
+		c.received(len(data))
+	}
+	return data, err
+}
+
+// WriteMessage writes a message and records its size.
+func (c *meteredCodec) WriteMessage(data []byte) error {
+	if err := c.Codec.WriteMessage(data); err != nil {
+		return err
+	}
+	c.sent(len(data))
+	return nil
+}
+
+// ReadJSON reads and unmarshals the next message into v.
+func (c *meteredCodec) ReadJSON(v interface{}) error {
+	data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("json unmarshal error: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON marshals v and writes it as a single message.
+func (c *meteredCodec) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.WriteMessage(data)
+}
+
+// WriteJSONWithFiles marshals v and sends it with files attached.
+func (c *meteredCodec) WriteJSONWithFiles(v interface{}, files []*os.File) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.writeMessageWithFiles(data, files)
+}
+
+// writeMessageWithFiles writes a raw message with files attached and
+// records its size.
+func (c *meteredCodec) writeMessageWithFiles(data []byte, files []*os.File) error {
+	fc, ok := filesSupported(c.Codec)
+	if !ok {
+		return ErrFilePassingUnsupported
+	}
+	if err := fc.writeMessageWithFiles(data, files); err != nil {
+		return err
+	}
+	c.sent(len(data))
+	return nil
+}
+
+// takeFiles removes the next n received files from the wrapped codec.
+func (c *meteredCodec) takeFiles(n int) ([]*os.File, error) {
+	fc, ok := filesSupported(c.Codec)
+	if !ok {
+		if n <= 0 {
+			return nil, nil
+		}
+		return nil, ErrFilePassingUnsupported
+	}
+	return fc.takeFiles(n)
+}
+
+// discardFiles closes the unclaimed files of the wrapped codec.
+func (c *meteredCodec) discardFiles() {
+	if fc, ok := filesSupported(c.Codec); ok {
+		fc.discardFiles()
+	}
+}
+
+// supportsFiles reports whether the wrapped codec can carry files.
+func (c *meteredCodec) supportsFiles() bool {
+	_, ok := filesSupported(c.Codec)
+	return ok
+}
@@ -0,0 +1,199 @@
+package jsonrpcipc
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	m := newMetrics()
+	m.requestStarted()
+	m.requestFinished("add", 2*time.Millisecond, 0)
+	m.requestStarted()
+	m.requestFinished("add", 20*time.Millisecond, InvalidParams)
+	m.requestStarted()
+	m.connectionOpened()
+	m.received(10)
+	m.sent(25)
+	m.broadcast(3)
+
+	out := m.String()
+
+	for _, want := range []string{
+		"# TYPE jsonrpc_requests_total counter\n",
+		`jsonrpc_requests_total{method="add"} 2` + "\n",
+		`jsonrpc_request_errors_total{method="add",code="-32602"} 1` + "\n",
+		"# TYPE jsonrpc_request_duration_seconds histogram\n",
+		`jsonrpc_request_duration_seconds_bucket{method="add",le="0.001"} 0` + "\n",
+		`jsonrpc_request_duration_seconds_bucket{method="add",le="0.0025"} 1` + "\n",
+		`jsonrpc_request_duration_seconds_bucket{method="add",le="0.025"} 2` + "\n",
+		`jsonrpc_request_duration_seconds_bucket{method="add",le="+Inf"} 2` + "\n",
+		`jsonrpc_request_duration_seconds_sum{method="add"} 0.022` + "\n",
+		`jsonrpc_request_duration_seconds_count{method="add"} 2` + "\n",
+		"jsonrpc_requests_in_flight 1\n",
+		"jsonrpc_connections 1\n",
+		"jsonrpc_connections_total 1\n",
+		"jsonrpc_received_bytes_total 10\n",
+		"jsonrpc_sent_bytes_total 25\n",
+		"jsonrpc_broadcasts_total 1\n",
+		"jsonrpc_broadcast_recipients_total 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_LabelEscaping(t *testing.T) {
+	m := newMetrics()
+	m.requestStarted()
+	m.requestFinished("a\"b\\c\nd", time.Millisecond, 0)
+
+	want := `jsonrpc_requests_total{method="a\"b\\c\nd"} 1`
+	if out := m.String(); !strings.Contains(out, want) {
+		t.Errorf("Output missing %q\n%s", want, out)
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := newMetrics()
+	m.connectionOpened()
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want text/plain; version=0.0.4", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "jsonrpc_connections 1\n") {
+		t.Errorf("Body missing connection gauge:\n%s", rec.Body.String())
+	}
+}
+
+func TestMetrics_NilSafe(t *testing.T) {
+	var m *Metrics
+	m.requestStarted()
+	m.requestFinished("add", time.Millisecond, 0)
+	m.connectionOpened()
+	m.connectionClosed()
+	m.received(1)
+	m.sent(1)
+	m.broadcast(1)
+}
+
+func TestServer_MetricsMethod(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+
+	if _, err := callAdd(t, conn); err != nil {
+		t.Fatalf("call error: %v", err)
+	}
+
+	codec := NewCodec(conn)
+	codec.WriteJSON(&Request{JSONRPC: "2.0", Method: "missing", ID: 2})
+	var resp Response
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+
+	codec.WriteJSON(&Request{JSONRPC: "2.0", Method: MetricsMethod, ID: 3})
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	out, ok := resp.Result.(string)
+	if !ok {
+		t.Fatalf("Result = %v, want metrics text", resp.Result)
+	}
+
+	for _, want := range []string{
+		`jsonrpc_requests_total{method="add"} 1`,
+		`jsonrpc_request_errors_total{method="(unknown)",code="-32601"} 1`,
+		"jsonrpc_connections 1\n",
+		"jsonrpc_requests_in_flight 1\n", // The $/metrics request itself
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "jsonrpc_received_bytes_total 0\n") || strings.Contains(out, "jsonrpc_sent_bytes_total 0\n") {
+		t.Errorf("Traffic not counted:\n%s", out)
+	}
+
+	// Built-in methods are not listed as registered methods
+	for _, method := range server.Methods() {
+		if method == MetricsMethod {
+			t.Errorf("Methods() includes %q", MetricsMethod)
+		}
+	}
+}
+
+func TestServer_MetricsBroadcast(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.ConnectionCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Connection was not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.Broadcast("tick", nil)
+
+	var notif Notification
+	if err := NewCodec(conn).ReadJSON(&notif); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+
+	out := server.Metrics().String()
+	if !strings.Contains(out, "jsonrpc_broadcast_recipients_total 1\n") {
+		t.Errorf("Broadcast fan-out not counted:\n%s", out)
+	}
+}
+
+func TestMeteredCodec_CountsBytes(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	m := newMetrics()
+	stats := &connectionStats{}
+	codec := &meteredCodec{Codec: NewCodec(conn1), stats: stats, metrics: m}
+
+	go NewCodec(conn2).WriteMessage([]byte(`{"jsonrpc":"2.0"}`))
+	if _, err := codec.ReadMessage(); err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	go NewCodec(conn2).ReadMessage()
+	if err := codec.WriteJSON(json.RawMessage(`{"a":1}`)); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	if got := m.bytesIn.Load(); got != 17 {
+		t.Errorf("bytesIn = %d, want 17", got)
+	}
+	if got := m.bytesOut.Load(); got != 7 {
+		t.Errorf("bytesOut = %d, want 7", got)
+	}
+	if in, out := stats.bytesIn.Load(), stats.bytesOut.Load(); in != 17 || out != 7 {
+		t.Errorf("connection bytes = %d in, %d out, want 17 in, 7 out", in, out)
+	}
+	if codec.supportsFiles() {
+		t.Error("supportsFiles() = true for a non-Unix connection")
+	}
+}
@@ -3,6 +3,10 @@ package jsonrpcipc
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 )
 
@@ -87,6 +91,46 @@ func LoggingMiddleware(logger Logger) Middleware {
 	}
 }
 
+// SlogMiddleware creates middleware that logs each request with log/slog.
+//
+// If logger is nil, the request-scoped logger is used (see LoggerFromContext),
+// so entries carry the method, request_id and conn_id attributes and go to
+// ServerConfig.SlogLogger. Otherwise logger is used with the same attributes.
+//
+// Successful requests are logged at Info level and failed ones at Error level,
+// with the duration and the JSON-RPC error code.
+//
+// Example:
+//
+//	server.RegisterMiddleware(SlogMiddleware(nil))
+func SlogMiddleware(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			log := logger
+			if log == nil {
+				log = LoggerFromContext(ctx)
+			} else {
+				log = log.With("method", MethodFromContext(ctx), "request_id", RequestIDFromContext(ctx))
+				if conn := ConnectionFromContext(ctx); conn != nil {
+					log = log.With("conn_id", conn.id)
+				}
+			}
+			start := time.Now()
+
+			result, err := next.Handle(ctx, params)
+			duration := time.Since(start)
+
+			if err != nil {
+				log.ErrorContext(ctx, "request failed", "duration", duration, "code", ToRPCError(err).Code, "error", err)
+			} else {
+				log.InfoContext(ctx, "request completed", "duration", duration)
+			}
+
+			return result, err
+		})
+	}
+}
+
 // RecoveryMiddleware creates a middleware that recovers from panics.
 //
 // If a handler panics, this middleware catches it and returns an InternalError.
@@ -111,11 +155,66 @@ func RecoveryMiddleware() Middleware {
 
 // TimeoutMiddleware creates a middleware that enforces a timeout on handlers.
 //
-// If a handler takes longer than the specified duration, it returns a timeout error.
+// If a handler takes longer than the specified duration, the client receives
+// a RequestTimeout error. The handler's context is canceled, but a handler
+// that ignores it keeps running in the background; such abandoned handlers
+// are reported to ServerConfig.OnError and counted in the server metrics.
+// Use TimeoutMiddlewareWithConfig to limit them.
 func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return TimeoutMiddlewareWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutConfig configures TimeoutMiddlewareWithConfig.
+type TimeoutConfig struct {
+	// Timeout is how long a handler may run before the client receives a
+	// RequestTimeout error.
+	Timeout time.Duration
+
+	// MaxAbandoned limits, per method, how many handlers may still be running
+	// after their timeout. Once the limit is reached, new calls to the method
+	// are refused with ServerOverloaded until one of them returns.
+	// Zero means no limit.
+	MaxAbandoned int
+}
+
+// AbandonedHandlerError is reported to ServerConfig.OnError when the timeout
+// middleware stops waiting for a handler that is still running.
+type AbandonedHandlerError struct {
+	Method    string        // Method of the abandoned handler
+	Elapsed   time.Duration // How long the handler had run when it was abandoned
+	Abandoned int           // Handlers of Method still running, including this one
+}
+
+// Error implements the error interface.
+func (e *AbandonedHandlerError) Error() string {
+	return fmt.Sprintf("handler for %q abandoned after %v (%d still running)", e.Method, e.Elapsed, e.Abandoned)
+}
+
+// TimeoutMiddlewareWithConfig creates a timeout middleware with options.
+//
+// Example:
+//
+//	server.RegisterMiddleware(TimeoutMiddlewareWithConfig(TimeoutConfig{
+//	    Timeout:      10 * time.Second,
+//	    MaxAbandoned: 8,
+//	}))
+func TimeoutMiddlewareWithConfig(config TimeoutConfig) Middleware {
+	tracker := &abandonedHandlers{counts: make(map[string]int)}
+
 	return func(next Handler) Handler {
 		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
-			ctx, cancel := context.WithTimeout(ctx, timeout)
+			method := MethodFromContext(ctx)
+			if config.MaxAbandoned > 0 {
+				if n := tracker.count(method); n >= config.MaxAbandoned {
+					return nil, NewServerOverloadedError(map[string]interface{}{
+						"method":    method,
+						"abandoned": n,
+					})
+				}
+			}
+
+			parent := ctx
+			ctx, cancel := context.WithTimeout(ctx, config.Timeout)
 			defer cancel()
 
 			type result struct {
@@ -123,9 +222,21 @@ func TimeoutMiddleware(timeout time.Duration) Middleware {
 				err   error
 			}
 
+			start := time.Now()
 			resultChan := make(chan result, 1)
 
 			go func() {
+				// A panic here would crash the process, since no caller is
+				// on this goroutine's stack to recover it
+				defer func() {
+					if r := recover(); r != nil {
+						resultChan <- result{err: NewInternalError(map[string]interface{}{
+							"panic":  r,
+							"method": method,
+						})}
+					}
+				}()
+
 				value, err := next.Handle(ctx, params)
 				resultChan <- result{value: value, err: err}
 			}()
@@ -134,8 +245,95 @@ func TimeoutMiddleware(timeout time.Duration) Middleware {
 			case res := <-resultChan:
 				return res.value, res.err
 			case <-ctx.Done():
-				return nil, NewInternalError("request timeout")
 			}
+
+			// The handler may have returned just as the timeout fired; it is
+			// not abandoned then, and its result stands unless it gave up
+			// because of the timeout
+			select {
+			case res := <-resultChan:
+				if !errors.Is(res.err, ctx.Err()) {
+					return res.value, res.err
+				}
+				return nil, timeoutError(parent, method, config.Timeout)
+			default:
+			}
+
+			// The handler is still running: track it until it returns
+			abandoned := &AbandonedHandlerError{
+				Method:    method,
+				Elapsed:   time.Since(start),
+				Abandoned: tracker.add(method),
+			}
+			var server *Server
+			if conn := ConnectionFromContext(parent); conn != nil {
+				server = conn.server
+			}
+			if server != nil {
+				server.metrics.handlerAbandoned(method)
+				server.reportError(abandoned)
+			} else {
+				LoggerFromContext(parent).Warn("handler abandoned", "elapsed", abandoned.Elapsed, "abandoned", abandoned.Abandoned)
+			}
+
+			go func() {
+				res := <-resultChan
+				if fr, ok := res.value.(*FileResult); ok {
+					closeFiles(fr.Files)
+				}
+				tracker.done(method)
+				if server != nil {
+					server.metrics.abandonedFinished(method)
+				}
+				LoggerFromContext(parent).Warn("abandoned handler finished", "elapsed", time.Since(start))
+			}()
+
+			return nil, timeoutError(parent, method, config.Timeout)
 		})
 	}
 }
+
+// timeoutError returns the error of a request whose handler did not finish
+// within timeout.
+func timeoutError(parent context.Context, method string, timeout time.Duration) error {
+	// The request was canceled from outside (connection closed or client
+	// deadline passed) rather than by the timeout
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return NewRequestTimeoutError(map[string]interface{}{
+		"method":  method,
+		"timeout": timeout.String(),
+	})
+}
+
+// abandonedHandlers counts, per method, the handlers still running after
+// their timeout.
+type abandonedHandlers struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// count returns the number of abandoned handlers of method.
+func (a *abandonedHandlers) count(method string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.counts[method]
+}
+
+// add records an abandoned handler and returns the new count.
+func (a *abandonedHandlers) add(method string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.counts[method]++
+	return a.counts[method]
+}
+
+// done records that an abandoned handler returned.
+func (a *abandonedHandlers) done(method string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.counts[method]--; a.counts[method] <= 0 {
+		delete(a.counts, method)
+	}
+}
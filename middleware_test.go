@@ -1,9 +1,14 @@
 package jsonrpcipc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -349,12 +354,13 @@ func TestTimeoutMiddleware(t *testing.T) {
 		t.Fatalf("Error type = %T, want *RPCError", err)
 	}
 
-	if rpcErr.Code != InternalError {
-		t.Errorf("Error code = %d, want %d", rpcErr.Code, InternalError)
+	if rpcErr.Code != RequestTimeout {
+		t.Errorf("Error code = %d, want %d", rpcErr.Code, RequestTimeout)
 	}
 
-	if rpcErr.Data != "request timeout" {
-		t.Errorf("Error data = %v, want %v", rpcErr.Data, "request timeout")
+	data, _ := rpcErr.Data.(map[string]interface{})
+	if data["timeout"] != "10ms" {
+		t.Errorf("Error data = %v, want timeout 10ms", rpcErr.Data)
 	}
 }
 
@@ -418,6 +424,118 @@ func TestTimeoutMiddleware_HandlerError(t *testing.T) {
 	}
 }
 
+func TestTimeoutMiddleware_MaxAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	baseHandler := HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		<-release // Ignores ctx
+		return "late", nil
+	})
+
+	handler := Chain(baseHandler, TimeoutMiddlewareWithConfig(TimeoutConfig{
+		Timeout:      10 * time.Millisecond,
+		MaxAbandoned: 1,
+	}))
+	ctx := WithMethod(context.Background(), "stuck")
+
+	_, err := handler.Handle(ctx, nil)
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.Code != RequestTimeout {
+		t.Fatalf("First call error = %v, want RequestTimeout", err)
+	}
+
+	// The abandoned handler is still running, so the method is refused
+	_, err = handler.Handle(ctx, nil)
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.Code != ServerOverloaded {
+		t.Fatalf("Second call error = %v, want ServerOverloaded", err)
+	}
+
+	// Other methods are not affected
+	close(release)
+	if _, err := handler.Handle(WithMethod(context.Background(), "other"), nil); err != nil {
+		t.Fatalf("Other method error = %v", err)
+	}
+
+	// Once the abandoned handler returns, the method is accepted again
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := handler.Handle(ctx, nil)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Method still refused after the abandoned handler returned: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTimeoutMiddleware_Panic(t *testing.T) {
+	baseHandler := HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		panic("boom")
+	})
+
+	handler := Chain(baseHandler, TimeoutMiddleware(time.Second))
+
+	_, err := handler.Handle(context.Background(), nil)
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.Code != InternalError {
+		t.Fatalf("Error = %v, want InternalError", err)
+	}
+}
+
+func TestTimeoutMiddleware_ReportsAbandoned(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	reported := make(chan error, 1)
+	server, err := NewServer(ServerConfig{
+		SocketPath: "tcp://127.0.0.1:0",
+		SlogLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		OnError:    func(err error) { reported <- err },
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	release := make(chan struct{})
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("stuck", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	connection := newConnection(conn1, registry, []Middleware{TimeoutMiddleware(10 * time.Millisecond)}, server)
+	reply, _ := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"stuck","id":1}`))
+	if errResp, ok := reply.(*ErrorResponse); !ok || errResp.Error.Code != RequestTimeout {
+		t.Fatalf("Reply = %#v, want RequestTimeout", reply)
+	}
+
+	select {
+	case err := <-reported:
+		var abandoned *AbandonedHandlerError
+		if !errors.As(err, &abandoned) {
+			t.Fatalf("OnError got %v, want *AbandonedHandlerError", err)
+		}
+		if abandoned.Method != "stuck" || abandoned.Abandoned != 1 || abandoned.Elapsed < 10*time.Millisecond {
+			t.Errorf("AbandonedHandlerError = %+v", abandoned)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Abandoned handler was not reported")
+	}
+
+	if out := server.Metrics().String(); !strings.Contains(out, `jsonrpc_abandoned_handlers{method="stuck"} 1`) {
+		t.Errorf("Abandoned handler not in metrics:\n%s", out)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(server.Metrics().String(), `jsonrpc_abandoned_handlers{method="stuck"} 0`) {
+		if time.Now().After(deadline) {
+			t.Fatal("Abandoned handler still counted after it returned")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 func TestMiddleware_Combination(t *testing.T) {
 	var loggedMethod string
 	var loggedErr error
@@ -512,3 +630,123 @@ func TestMiddleware_OrderMatters(t *testing.T) {
 		}
 	}
 }
+
+// logBuffer collects log output; it is safe for concurrent use.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the logged JSON records.
+func (b *logBuffer) records(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// find returns the first record with the given message, or nil.
+func (b *logBuffer) find(t *testing.T, msg string) map[string]interface{} {
+	t.Helper()
+	for _, record := range b.records(t) {
+		if record["msg"] == msg {
+			return record
+		}
+	}
+	return nil
+}
+
+// newBufferLogger returns a debug-level JSON logger writing to a logBuffer.
+func newBufferLogger() (*slog.Logger, *logBuffer) {
+	buf := &logBuffer{}
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})), buf
+}
+
+func TestSlogMiddleware(t *testing.T) {
+	logger, buf := newBufferLogger()
+
+	handler := Chain(HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "ok", nil
+	}), SlogMiddleware(logger))
+
+	ctx := WithRequestID(WithMethod(context.Background(), "test.method"), 7)
+	if _, err := handler.Handle(ctx, nil); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	record := buf.find(t, "request completed")
+	if record == nil {
+		t.Fatal("No \"request completed\" record logged")
+	}
+	if record["level"] != "INFO" {
+		t.Errorf("level = %v, want INFO", record["level"])
+	}
+	if record["method"] != "test.method" {
+		t.Errorf("method = %v, want test.method", record["method"])
+	}
+	if record["request_id"] != float64(7) {
+		t.Errorf("request_id = %v, want 7", record["request_id"])
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("duration attribute missing")
+	}
+}
+
+func TestSlogMiddleware_Error(t *testing.T) {
+	logger, buf := newBufferLogger()
+
+	handler := Chain(HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, NewInvalidParamsError("bad")
+	}), SlogMiddleware(logger))
+
+	handler.Handle(WithMethod(context.Background(), "test.method"), nil)
+
+	record := buf.find(t, "request failed")
+	if record == nil {
+		t.Fatal("No \"request failed\" record logged")
+	}
+	if record["level"] != "ERROR" {
+		t.Errorf("level = %v, want ERROR", record["level"])
+	}
+	if record["code"] != float64(InvalidParams) {
+		t.Errorf("code = %v, want %d", record["code"], InvalidParams)
+	}
+}
+
+func TestSlogMiddleware_ContextLogger(t *testing.T) {
+	logger, buf := newBufferLogger()
+
+	handler := Chain(HandlerFunc(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "ok", nil
+	}), SlogMiddleware(nil))
+
+	// With a nil logger, the request-scoped logger and its attributes are used
+	ctx := WithLogger(context.Background(), logger.With("conn_id", 42))
+	handler.Handle(ctx, nil)
+
+	record := buf.find(t, "request completed")
+	if record == nil {
+		t.Fatal("No \"request completed\" record logged")
+	}
+	if record["conn_id"] != float64(42) {
+		t.Errorf("conn_id = %v, want 42", record["conn_id"])
+	}
+}
@@ -174,19 +174,28 @@ export class IPCTransport extends EventEmitter implements Transport {
       return `\\\\.\\pipe\\${path}`;
     }
 
+    // Linux abstract socket: "@name" is written as a leading NUL byte in Node
+    if (path.startsWith('@')) {
+      return `\0${path.slice(1)}`;
+    }
+
     // Unix/Linux/Mac socket path normalization
     // If already has directory separator, keep it
     if (path.includes('/')) {
       return path;
     }
 
-    // If has .sock extension, prepend /tmp/
+    // Simple names live in $XDG_RUNTIME_DIR, or a per-user directory under /tmp.
+    // This matches the Go server's default socket location.
+    const dir = process.env.XDG_RUNTIME_DIR || `/tmp/ipc-jsonrpc-${process.getuid?.() ?? 0}`;
+
+    // If has .sock extension, prepend the socket directory
     if (path.endsWith('.sock')) {
-      return `/tmp/${path}`;
+      return `${dir}/${path}`;
     }
 
-    // Simple name - convert to /tmp/{name}.sock
-    return `/tmp/${path}.sock`;
+    // Simple name - convert to {dir}/{name}.sock
+    return `${dir}/${path}.sock`;
   }
 
   /**
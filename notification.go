@@ -2,6 +2,7 @@ package jsonrpcipc
 
 import (
 	"fmt"
+	"os"
 	"sync"
 )
 
@@ -10,14 +11,14 @@ import (
 // Notifications are JSON-RPC messages without an ID field, meaning they
 // don't expect a response from the client.
 type NotificationManager struct {
-	codec   *LineDelimitedCodec
+	codec   Codec
 	mu      sync.Mutex // Protects writes to codec
 	closed  bool
 	closeMu sync.RWMutex
 }
 
 // NewNotificationManager creates a new notification manager.
-func NewNotificationManager(codec *LineDelimitedCodec) *NotificationManager {
+func NewNotificationManager(codec Codec) *NotificationManager {
 	return &NotificationManager{
 		codec: codec,
 	}
@@ -33,6 +34,35 @@ func NewNotificationManager(codec *LineDelimitedCodec) *NotificationManager {
 //
 // Thread-safety: This method is safe to call concurrently.
 func (nm *NotificationManager) Send(method string, params interface{}) error {
+	return nm.send(&Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// send writes a fully built notification.
+func (nm *NotificationManager) send(notification *Notification) error {
+	nm.closeMu.RLock()
+	if nm.closed {
+		nm.closeMu.RUnlock()
+		return fmt.Errorf("notification manager is closed")
+	}
+	nm.closeMu.RUnlock()
+
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+
+	return nm.codec.WriteJSON(notification)
+}
+
+// SendWithFiles sends a notification with files attached (Unix sockets only).
+// The notification's "fds" member is set to the number of files.
+//
+// Returns ErrFilePassingUnsupported if the codec cannot carry files.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (nm *NotificationManager) SendWithFiles(method string, params interface{}, files []*os.File) error {
 	nm.closeMu.RLock()
 	if nm.closed {
 		nm.closeMu.RUnlock()
@@ -40,16 +70,22 @@ func (nm *NotificationManager) Send(method string, params interface{}) error {
 	}
 	nm.closeMu.RUnlock()
 
+	codec, ok := filesSupported(nm.codec)
+	if !ok {
+		return ErrFilePassingUnsupported
+	}
+
 	notification := &Notification{
 		JSONRPC: "2.0",
 		Method:  method,
 		Params:  params,
+		FDs:     len(files),
 	}
 
 	nm.mu.Lock()
 	defer nm.mu.Unlock()
 
-	return nm.codec.WriteJSON(notification)
+	return codec.WriteJSONWithFiles(notification, files)
 }
 
 // Close marks the notification manager as closed.
@@ -96,17 +132,93 @@ func (bm *BroadcastManager) Remove(conn *Connection) {
 // Returns the number of connections the notification was sent to.
 // Errors sending to individual connections are logged but don't stop the broadcast.
 func (bm *BroadcastManager) Broadcast(method string, params interface{}) int {
-	count := 0
+	return bm.BroadcastWithReport(method, params).Sent
+}
+
+// BroadcastWithReport sends a notification to all connected clients and
+// reports which connections it could not be sent to.
+func (bm *BroadcastManager) BroadcastWithReport(method string, params interface{}) *BroadcastReport {
+	return bm.broadcastIf(nil, method, params)
+}
+
+// BroadcastFilter sends a notification to the connections for which include
+// returns true.
+//
+// Returns the number of connections the notification was sent to.
+func (bm *BroadcastManager) BroadcastFilter(include func(*Connection) bool, method string, params interface{}) int {
+	return bm.broadcastIf(include, method, params).Sent
+}
+
+// broadcastIf sends a notification to the connections selected by include,
+// or to all connections if include is nil.
+func (bm *BroadcastManager) broadcastIf(include func(*Connection) bool, method string, params interface{}) *BroadcastReport {
+	report := &BroadcastReport{Method: method}
 
 	bm.connections.Range(func(key, value interface{}) bool {
 		conn := key.(*Connection)
-		if err := conn.Notify(method, params); err == nil {
-			count++
+		if include != nil && !include(conn) {
+			return true
+		}
+		if err := conn.Notify(method, params); err != nil {
+			report.Failed = append(report.Failed, BroadcastFailure{Conn: conn, Err: err})
+		} else {
+			report.Sent++
 		}
 		return true // Continue iteration
 	})
 
-	return count
+	return report
+}
+
+// BroadcastReport is the outcome of a broadcast.
+//
+// Notifications are queued per connection (see ServerConfig.WriteQueuePolicy),
+// so a failure means the notification was not queued: the connection was
+// closed, or its queue was full and the notification dropped. Errors writing
+// a queued notification are not reported.
+type BroadcastReport struct {
+	Method string             // Method of the notification
+	Sent   int                // Connections the notification was queued for
+	Failed []BroadcastFailure // Connections it could not be queued for
+}
+
+// BroadcastFailure is a connection a broadcast did not reach.
+type BroadcastFailure struct {
+	Conn *Connection
+	Err  error
+}
+
+// Err returns a *BroadcastError describing the failures, or nil if the
+// broadcast reached every selected connection.
+func (r *BroadcastReport) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	return &BroadcastError{Method: r.Method, Sent: r.Sent, Failed: r.Failed}
+}
+
+// BroadcastError is reported to ServerConfig.OnError, when
+// ServerConfig.ReportBroadcastErrors is set, for a broadcast that did not
+// reach every selected connection.
+type BroadcastError struct {
+	Method string
+	Sent   int
+	Failed []BroadcastFailure
+}
+
+// Error implements the error interface.
+func (e *BroadcastError) Error() string {
+	return fmt.Sprintf("broadcast %q failed for %d of %d connections: %v",
+		e.Method, len(e.Failed), e.Sent+len(e.Failed), e.Failed[0].Err)
+}
+
+// Unwrap returns the errors of the failed connections.
+func (e *BroadcastError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+	return errs
 }
 
 // Count returns the number of active connections.
@@ -2,6 +2,7 @@ package jsonrpcipc
 
 import (
 	"encoding/json"
+	"errors"
 	"net"
 	"sync"
 	"testing"
@@ -400,3 +401,48 @@ func TestBroadcastManager_Remove_NonExistent(t *testing.T) {
 		t.Errorf("Count() = %d, want 0", bm.Count())
 	}
 }
+
+func TestBroadcastManager_BroadcastWithReport(t *testing.T) {
+	bm := NewBroadcastManager()
+	clients := newGroupTestClients(t, bm, 3)
+	clients[1].conn.Close()
+
+	report := bm.BroadcastWithReport("status", nil)
+	if report.Sent != 2 {
+		t.Errorf("Sent = %d, want 2", report.Sent)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Conn != clients[1].conn || report.Failed[0].Err == nil {
+		t.Fatalf("Failed = %v, want the closed connection", report.Failed)
+	}
+
+	var bErr *BroadcastError
+	if err := report.Err(); !errors.As(err, &bErr) {
+		t.Fatalf("Err() = %v, want *BroadcastError", err)
+	}
+	if !errors.Is(bErr, errConnectionClosed) {
+		t.Errorf("BroadcastError does not wrap the connection error: %v", bErr)
+	}
+	if want := `broadcast "status" failed for 1 of 3 connections: connection is closed`; bErr.Error() != want {
+		t.Errorf("Error() = %q, want %q", bErr.Error(), want)
+	}
+	expectNotified(t, clients, "status", true, false, true)
+
+	clean := NewBroadcastManager()
+	newGroupTestClients(t, clean, 1)
+	if err := clean.BroadcastWithReport("status", nil).Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+func TestBroadcastManager_BroadcastFilter(t *testing.T) {
+	bm := NewBroadcastManager()
+	clients := newGroupTestClients(t, bm, 3)
+
+	count := bm.BroadcastFilter(func(c *Connection) bool {
+		return c != clients[0].conn
+	}, "filtered", nil)
+	if count != 2 {
+		t.Errorf("BroadcastFilter() count = %d, want 2", count)
+	}
+	expectNotified(t, clients, "filtered", false, true, true)
+}
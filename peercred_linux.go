@@ -0,0 +1,31 @@
+//go:build linux
+
+package jsonrpcipc
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials reads the credentials of the process at the other end of a
+// Unix socket with SO_PEERCRED. They are those of the process that connected.
+func peerCredentials(conn net.Conn) (PeerCredentials, bool) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return PeerCredentials{}, false
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return PeerCredentials{}, false
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		return PeerCredentials{}, false
+	}
+	return PeerCredentials{PID: int(cred.Pid), UID: cred.Uid, GID: cred.Gid}, true
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package jsonrpcipc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_ConnectionsPeerCredentials(t *testing.T) {
+	socketPath := "unix://" + filepath.Join(t.TempDir(), "peer.sock")
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: socketPath})
+
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer client.Close()
+
+	info := waitForConnections(t, server, 1)[0]
+	if info.Peer == nil {
+		t.Fatal("Peer = nil for a Unix socket client")
+	}
+	if info.Peer.PID != os.Getpid() || info.Peer.UID != uint32(os.Getuid()) || info.Peer.GID != uint32(os.Getgid()) {
+		t.Errorf("Peer = %+v, want pid %d uid %d gid %d", *info.Peer, os.Getpid(), os.Getuid(), os.Getgid())
+	}
+
+	cred, ok := server.Connection(info.ID).PeerCredentials()
+	if !ok || cred != *info.Peer {
+		t.Errorf("PeerCredentials() = %+v, %v, want %+v", cred, ok, *info.Peer)
+	}
+}
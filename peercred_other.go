@@ -0,0 +1,10 @@
+//go:build !linux
+
+package jsonrpcipc
+
+import "net"
+
+// peerCredentials is only implemented on Linux (SO_PEERCRED).
+func peerCredentials(conn net.Conn) (PeerCredentials, bool) {
+	return PeerCredentials{}, false
+}
@@ -0,0 +1,178 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Built-in methods for topic subscriptions. Clients subscribe to topic
+// names or patterns (see matchGlob), and Server.Publish delivers a
+// notification, whose method is the topic, only to matching subscribers:
+//
+//	→ {"jsonrpc":"2.0","method":"$/subscribe","params":{"topics":["build.*"]},"id":1}
+//	← {"jsonrpc":"2.0","result":{"topics":["build.*"]},"id":1}
+//	← {"jsonrpc":"2.0","method":"build.started","params":{"target":"all"}}
+const (
+	SubscribeMethod   = "$/subscribe"
+	UnsubscribeMethod = "$/unsubscribe"
+)
+
+// Limits on client subscriptions.
+const (
+	maxSubscriptions   = 256 // Per connection
+	maxTopicPatternLen = 256
+)
+
+// subscriptionParams are the params of $/subscribe and $/unsubscribe.
+type subscriptionParams struct {
+	Topics []string `json:"topics"`
+}
+
+// subscriptionResult is the result of $/subscribe and $/unsubscribe:
+// the connection's subscriptions after the call.
+type subscriptionResult struct {
+	Topics []string `json:"topics"`
+}
+
+// Subscribe subscribes the connection to a topic name or pattern
+// (for example "build.*"). Subscribing twice to the same pattern has no
+// further effect. Subscriptions end when the connection closes.
+//
+// Handlers usually let clients call the built-in "$/subscribe" method
+// instead; Subscribe is for subscribing clients from the server side.
+func (c *Connection) Subscribe(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("topic must not be empty")
+	}
+	if len(pattern) > maxTopicPatternLen {
+		return fmt.Errorf("topic longer than %d bytes", maxTopicPatternLen)
+	}
+
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+
+	if _, ok := c.subscriptions[pattern]; ok {
+		return nil
+	}
+	if len(c.subscriptions) >= maxSubscriptions {
+		return fmt.Errorf("too many subscriptions (maximum %d)", maxSubscriptions)
+	}
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]struct{})
+	}
+	c.subscriptions[pattern] = struct{}{}
+	return nil
+}
+
+// Unsubscribe removes a subscription added with Subscribe or "$/subscribe".
+// The pattern must be exactly the one that was subscribed.
+func (c *Connection) Unsubscribe(pattern string) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	delete(c.subscriptions, pattern)
+}
+
+// Subscriptions returns the connection's topic subscriptions, sorted.
+func (c *Connection) Subscriptions() []string {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+
+	topics := make([]string, 0, len(c.subscriptions))
+	for pattern := range c.subscriptions {
+		topics = append(topics, pattern)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// subscribedTo reports whether any subscription matches topic.
+func (c *Connection) subscribedTo(topic string) bool {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+
+	if _, ok := c.subscriptions[topic]; ok {
+		return true
+	}
+	for pattern := range c.subscriptions {
+		if isGlob(pattern) && matchGlob(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish sends a notification with the topic as its method to every
+// connection subscribed to the topic.
+//
+// Returns the number of connections the notification was sent to.
+func (bm *BroadcastManager) Publish(topic string, params interface{}) int {
+	return bm.BroadcastFilter(subscribersOf(topic), topic, params)
+}
+
+// subscribersOf selects the connections subscribed to topic.
+func subscribersOf(topic string) func(*Connection) bool {
+	return func(conn *Connection) bool {
+		return conn.subscribedTo(topic)
+	}
+}
+
+// Publish sends a notification to the clients subscribed to topic, through
+// the built-in "$/subscribe" method or Connection.Subscribe. The
+// notification's method is the topic.
+//
+// Example:
+//
+//	n := server.Publish("build.finished", map[string]interface{}{"ok": true})
+//	log.Printf("Delivered to %d subscribers", n)
+//
+// Returns the number of connections the notification was sent to.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) Publish(topic string, params interface{}) int {
+	return s.BroadcastFilter(subscribersOf(topic), topic, params)
+}
+
+// subscribeHandler implements the built-in "$/subscribe" method.
+func subscribeHandler(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	conn, topics, err := subscriptionRequest(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range topics {
+		if err := conn.Subscribe(topic); err != nil {
+			return nil, NewInvalidParamsError(err.Error())
+		}
+	}
+	return subscriptionResult{Topics: conn.Subscriptions()}, nil
+}
+
+// unsubscribeHandler implements the built-in "$/unsubscribe" method.
+func unsubscribeHandler(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	conn, topics, err := subscriptionRequest(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range topics {
+		conn.Unsubscribe(topic)
+	}
+	return subscriptionResult{Topics: conn.Subscriptions()}, nil
+}
+
+// subscriptionRequest decodes the params of a subscription method.
+func subscriptionRequest(ctx context.Context, params json.RawMessage) (*Connection, []string, error) {
+	conn := ConnectionFromContext(ctx)
+	if conn == nil {
+		return nil, nil, NewInternalError("no connection in context")
+	}
+
+	var p subscriptionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, nil, NewInvalidParamsError(err.Error())
+	}
+	if len(p.Topics) == 0 {
+		return nil, nil, NewInvalidParamsError("topics must not be empty")
+	}
+	return conn, p.Topics, nil
+}
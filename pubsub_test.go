@@ -0,0 +1,184 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnection_Subscribe(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+
+	if err := connection.Subscribe(""); err == nil {
+		t.Error("Subscribe(\"\") should fail")
+	}
+	if err := connection.Subscribe(strings.Repeat("a", maxTopicPatternLen+1)); err == nil {
+		t.Error("Subscribe() with an overlong pattern should fail")
+	}
+
+	connection.Subscribe("log")
+	connection.Subscribe("build.*")
+	connection.Subscribe("build.*")
+
+	if got := connection.Subscriptions(); len(got) != 2 || got[0] != "build.*" || got[1] != "log" {
+		t.Errorf("Subscriptions() = %v, want [build.* log]", got)
+	}
+	if !connection.subscribedTo("build.started") || !connection.subscribedTo("log") {
+		t.Error("subscribedTo() = false for a subscribed topic")
+	}
+	if connection.subscribedTo("deploy.started") {
+		t.Error("subscribedTo(deploy.started) = true, want false")
+	}
+
+	connection.Unsubscribe("build.*")
+	if connection.subscribedTo("build.started") {
+		t.Error("subscribedTo() = true after Unsubscribe")
+	}
+}
+
+func TestConnection_SubscribeLimit(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, nil)
+	for i := 0; i < maxSubscriptions; i++ {
+		if err := connection.Subscribe(fmt.Sprintf("topic.%d", i)); err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+	}
+	if err := connection.Subscribe("one.more"); err == nil {
+		t.Error("Subscribe() beyond the limit should fail")
+	}
+}
+
+// subscribeClient dials addr and subscribes to topics through $/subscribe.
+func subscribeClient(t *testing.T, addr string, topics ...string) (net.Conn, *LineDelimitedCodec) {
+	t.Helper()
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	codec := NewCodec(conn)
+	params, _ := json.Marshal(subscriptionParams{Topics: topics})
+	if err := codec.WriteJSON(&Request{JSONRPC: "2.0", Method: SubscribeMethod, Params: params, ID: 1}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var resp Response
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Result == nil {
+		t.Fatalf("$/subscribe failed: %+v", resp)
+	}
+	return conn, codec
+}
+
+func TestServer_Publish(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	_, subscriber := subscribeClient(t, addr, "build.*")
+	_, other := subscribeClient(t, addr, "deploy.*")
+
+	if n := server.Publish("build.started", map[string]string{"target": "all"}); n != 1 {
+		t.Fatalf("Publish() = %d, want 1", n)
+	}
+
+	var notif Notification
+	if err := subscriber.ReadJSON(&notif); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if notif.Method != "build.started" {
+		t.Errorf("Notification method = %q, want build.started", notif.Method)
+	}
+
+	// The other client only receives its own topics
+	server.Publish("deploy.done", nil)
+	if err := other.ReadJSON(&notif); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if notif.Method != "deploy.done" {
+		t.Errorf("Other client got %q, want deploy.done", notif.Method)
+	}
+}
+
+func TestServer_Unsubscribe(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	_, codec := subscribeClient(t, addr, "build.*", "log")
+
+	params, _ := json.Marshal(subscriptionParams{Topics: []string{"build.*"}})
+	codec.WriteJSON(&Request{JSONRPC: "2.0", Method: UnsubscribeMethod, Params: params, ID: 2})
+
+	var resp struct {
+		Result subscriptionResult `json:"result"`
+	}
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if len(resp.Result.Topics) != 1 || resp.Result.Topics[0] != "log" {
+		t.Errorf("Subscriptions after unsubscribe = %v, want [log]", resp.Result.Topics)
+	}
+
+	if n := server.Publish("build.started", nil); n != 0 {
+		t.Errorf("Publish() after unsubscribe = %d, want 0", n)
+	}
+}
+
+func TestServer_SubscribeInvalidParams(t *testing.T) {
+	_, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+
+	codec := NewCodec(conn)
+	codec.WriteJSON(&Request{JSONRPC: "2.0", Method: SubscribeMethod, Params: json.RawMessage(`{"topics":[]}`), ID: 1})
+
+	var resp ErrorResponse
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != InvalidParams {
+		t.Errorf("Response = %+v, want InvalidParams", resp)
+	}
+}
+
+func TestServer_PublishAfterDisconnect(t *testing.T) {
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	conn, _ := subscribeClient(t, addr, "build.*")
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.ConnectionCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Connection was not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := server.Publish("build.started", nil); n != 0 {
+		t.Errorf("Publish() after disconnect = %d, want 0", n)
+	}
+}
+
+func TestSubscribeHandler_NoConnection(t *testing.T) {
+	_, err := subscribeHandler(context.Background(), json.RawMessage(`{"topics":["a"]}`))
+	if err == nil {
+		t.Error("subscribeHandler() without a connection should fail")
+	}
+}
@@ -2,30 +2,60 @@ package jsonrpcipc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/exec"
 	"sync"
 	"time"
 )
 
+// tlsHandshakeTimeout bounds how long a client may take to complete the TLS handshake.
+const tlsHandshakeTimeout = 10 * time.Second
+
 // Server is a JSON-RPC 2.0 server over IPC.
 //
 // The server listens on a Unix socket (Linux/Mac) or Named Pipe (Windows)
 // and handles JSON-RPC requests from multiple clients concurrently.
 type Server struct {
 	config    ServerConfig
+	mu        sync.Mutex // Protects listener, ownsSocket and idle state
 	listener  net.Listener
 	registry  *HandlerRegistry
 	broadcast *BroadcastManager
+	metrics   *Metrics
+
+	// builtins are the "$/" methods served when no handler is registered
+	// under the same name
+	builtins map[string]Handler
 
 	middleware []Middleware
 
+	// ownsSocket is false for sockets inherited through socket activation,
+	// which belong to the service manager and must not be removed
+	ownsSocket bool
+
+	// Token generated at Start and the file it was written to (see
+	// AuthConfig)
+	authToken string
+	tokenFile string
+
+	// HTTP transport (see HTTPHandler)
+	http *HTTPHandler
+
 	// Connection management
 	connections sync.Map // map[*Connection]bool
 	wg          sync.WaitGroup
 
+	// Idle shutdown (see ServerConfig.IdleTimeout)
+	active    int
+	idleTimer *time.Timer
+
 	// Lifecycle
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -36,17 +66,123 @@ type Server struct {
 
 // ServerConfig holds configuration options for the Server.
 type ServerConfig struct {
-	// SocketPath is the path to the Unix socket or Windows named pipe.
+	// SocketPath is the path to the Unix socket or Windows named pipe,
+	// or a URL-style address for network transports.
 	//
 	// Examples:
-	//   - Unix/Linux/Mac: "/tmp/myapp.sock"
+	//   - Unix/Linux/Mac: "/tmp/myapp.sock" or "unix:///tmp/myapp.sock"
 	//   - Windows: "myapp" (automatically converted to "\\.\pipe\myapp")
+	//   - TCP: "tcp://127.0.0.1:7000"
+	//   - TLS: "tls://127.0.0.1:7000" (requires TLSConfig)
 	SocketPath string
 
-	// Logger is used for server logging.
-	// If nil, a default logger is used.
+	// TLSConfig is the server TLS configuration, required for "tls://" addresses.
+	TLSConfig *tls.Config
+
+	// TLSClientCAs enables client-certificate authentication for "tls://"
+	// addresses. Clients must present a certificate signed by one of these CAs.
+	// Optional.
+	TLSClientCAs *x509.CertPool
+
+	// SocketMode sets the permission bits of the socket file (for example 0600
+	// to restrict access to the owning user). Zero keeps the umask default.
+	// Unix only.
+	SocketMode os.FileMode
+
+	// SocketGroup sets the group ownership of the socket file, by name or
+	// numeric GID. Combine with SocketMode 0660 and an explicit socket path to
+	// share the socket with a group; bare names are placed in a directory
+	// only the owner can enter.
+	// Unix only.
+	SocketGroup string
+
+	// LockFile holds an exclusive lock file next to the socket while the
+	// server runs, guaranteeing a single instance per socket path.
+	// Unix only.
+	LockFile bool
+
+	// IdleTimeout stops the server once it has had no connections for this
+	// long. Combined with systemd socket activation, the service manager
+	// starts the server again when the next client connects.
+	// Zero (the default) disables idle shutdown.
+	IdleTimeout time.Duration
+
+	// SlogLogger receives the server's internal events: startup and shutdown,
+	// accept and handshake errors, malformed messages and connection lifecycle.
+	// It is also the base of the request-scoped logger (see LoggerFromContext).
+	// If nil, slog.Default() is used.
+	SlogLogger *slog.Logger
+
+	// Logger is a per-request logging function.
+	// If nil, nothing is logged through it.
+	//
+	// Deprecated: Logger is not called by the server. Use SlogLogger, and
+	// LoggingMiddleware or SlogMiddleware for per-request logging.
 	Logger Logger
 
+	// MaxRequestTimeout caps the deadline a client may set for a request
+	// through the "deadline" and "timeout" meta keys. Zero leaves client
+	// deadlines uncapped. Requests without a deadline are not affected; use
+	// TimeoutMiddleware for a server-wide timeout.
+	MaxRequestTimeout time.Duration
+
+	// StrictSpec restricts the server to plain JSON-RPC 2.0: requests with
+	// the "meta" extension member are rejected with Invalid Request, and the
+	// server never sends "meta" in responses or notifications.
+	StrictSpec bool
+
+	// Tracer creates a span for each request, for example through an
+	// OpenTelemetry adapter. Requests carry their W3C trace context in the
+	// "meta" member (see TraceContext).
+	// Optional.
+	Tracer Tracer
+
+	// WriteQueueSize is the number of notifications each connection buffers
+	// for a client that reads slower than the server writes. Responses are
+	// queued in order with them and bounded separately by the same size:
+	// once a client has that many unread responses, the server stops reading
+	// its requests.
+	// If zero, DefaultWriteQueueSize is used.
+	WriteQueueSize int
+
+	// WriteQueuePolicy decides what happens to a notification sent to a
+	// connection whose write queue is full. The default, QueueDropOldest,
+	// discards the client's oldest queued notification, so Broadcast and
+	// Publish never wait for a stuck client. QueueBlock makes the sender wait
+	// instead, and stalls broadcasts to every client once one queue is full.
+	WriteQueuePolicy QueuePolicy
+
+	// Handshake requires clients to call InitializeMethod, agreeing on a
+	// protocol version and capabilities, before any other request. Requests
+	// sent earlier are refused with ServerNotInitialized.
+	// Optional.
+	Handshake *HandshakeConfig
+
+	// Auth requires clients to authenticate with a token, through
+	// AuthenticateMethod or MetaAuthToken, before any other request.
+	// Requests sent earlier are refused with Unauthenticated.
+	// Optional.
+	Auth *AuthConfig
+
+	// Lifecycle restricts the methods available in each connection state
+	// (see ConnectionState). With a Lifecycle, Stop also moves every
+	// connection to StateShuttingDown while it waits for them to finish.
+	// Optional.
+	Lifecycle *LifecycleConfig
+
+	// ReportBroadcastErrors reports broadcasts that miss connections, such as
+	// closed ones or ones whose write queue is full, to OnError as a
+	// *BroadcastError. It applies to Broadcast, BroadcastTo, BroadcastExcept,
+	// BroadcastFilter and Publish.
+	ReportBroadcastErrors bool
+
+	// CheckOrigin decides whether a request from a browser is allowed. It
+	// applies to WebSocket upgrades and to the POST requests and event
+	// streams of HTTPHandler. If nil, only requests without an Origin header or from
+	// the same host are accepted, so other web pages cannot call the server.
+	// Optional.
+	CheckOrigin func(r *http.Request) bool
+
 	// OnConnect is called when a new client connects.
 	// Optional.
 	OnConnect func(*Connection)
@@ -56,6 +192,7 @@ type ServerConfig struct {
 	OnDisconnect func(*Connection)
 
 	// OnError is called when an error occurs that isn't tied to a specific request.
+	// The error is also logged to SlogLogger.
 	// Optional.
 	OnError func(error)
 }
@@ -76,31 +213,40 @@ func NewServer(config ServerConfig) (*Server, error) {
 	if config.SocketPath == "" {
 		return nil, fmt.Errorf("SocketPath is required")
 	}
+	if scheme, _ := parseAddress(config.SocketPath); scheme == schemeTLS && config.TLSConfig == nil {
+		return nil, fmt.Errorf("TLSConfig is required for tls:// addresses")
+	}
 
 	// Set defaults
-	if config.Logger == nil {
-		config.Logger = func(method string, duration time.Duration, err error) {
-			if err != nil {
-				log.Printf("[JSON-RPC] method=%s duration=%v error=%v", method, duration, err)
-			}
-		}
-	}
-	if config.OnError == nil {
-		config.OnError = func(err error) {
-			log.Printf("[JSON-RPC] error: %v", err)
-		}
+	if config.SlogLogger == nil {
+		config.SlogLogger = slog.Default()
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Server{
+	s := &Server{
 		config:     config,
 		registry:   NewHandlerRegistry(),
 		broadcast:  NewBroadcastManager(),
+		metrics:    newMetrics(),
 		ctx:        ctx,
 		cancel:     cancel,
 		shutdownCh: make(chan struct{}),
-	}, nil
+	}
+	s.http = &HTTPHandler{server: s}
+	s.builtins = map[string]Handler{
+		MetricsMethod:     metricsHandler(s.metrics),
+		SubscribeMethod:   HandlerFunc(subscribeHandler),
+		UnsubscribeMethod: HandlerFunc(unsubscribeHandler),
+	}
+	if config.Handshake != nil {
+		s.builtins[InitializeMethod] = initializeHandler(config.Handshake)
+	}
+	if config.Auth != nil {
+		s.builtins[AuthenticateMethod] = HandlerFunc(authenticateHandler)
+	}
+
+	return s, nil
 }
 
 // RegisterHandler registers a handler for the specified JSON-RPC method.
@@ -141,6 +287,11 @@ func (s *Server) RegisterMiddleware(mw Middleware) {
 
 // Start starts the server and begins accepting connections.
 //
+// If the process was started by systemd socket activation (LISTEN_PID and
+// LISTEN_FDS are set for this process), the inherited socket is used instead
+// of creating a new one, and it is left in place when the server stops.
+// Likewise, a process started by Handoff adopts the previous server's socket.
+//
 // This method blocks until the server is stopped or an error occurs.
 //
 // Example:
@@ -152,23 +303,106 @@ func (s *Server) Start() error {
 	var err error
 
 	s.startOnce.Do(func() {
-		// Create listener
-		s.listener, err = Listen(s.config.SocketPath)
+		// Create or adopt listener
+		var listener net.Listener
+		var owned bool
+		listener, owned, err = s.listen()
 		if err != nil {
 			return
 		}
+		if err = s.setupAuth(); err != nil {
+			listener.Close()
+			return
+		}
 
-		log.Printf("[JSON-RPC] Server listening on %s", GetSocketPath(s.config.SocketPath))
+		s.mu.Lock()
+		s.listener = listener
+		s.ownsSocket = owned
+		if s.config.IdleTimeout > 0 {
+			s.idleTimer = time.AfterFunc(s.config.IdleTimeout, s.stopIfIdle)
+		}
+		s.mu.Unlock()
+
+		if !owned {
+			s.config.SlogLogger.Info("server listening", "address", listener.Addr().String(), "inherited", true)
+		} else if isNetworkAddress(s.config.SocketPath) {
+			s.config.SlogLogger.Info("server listening", "address", listener.Addr().String())
+		} else {
+			s.config.SlogLogger.Info("server listening", "address", GetSocketPath(s.config.SocketPath))
+		}
 
 		// Accept connections
-		err = s.acceptLoop()
+		if scheme, _ := parseAddress(s.config.SocketPath); scheme == schemeTLS {
+			listener = tls.NewListener(listener, s.tlsConfig())
+		}
+		// Counting the loop itself keeps its wg.Add calls from racing with
+		// the wg.Wait in Stop
+		s.wg.Add(1)
+		defer s.wg.Done()
+		err = s.acceptLoop(listener)
 	})
 
 	return err
 }
 
+// listen adopts the socket passed by a previous process (see Handoff) or by
+// systemd socket activation, or creates the listener itself. owned reports
+// whether the server created the socket and is therefore responsible for
+// removing it.
+//
+// The returned listener is never wrapped in TLS; Start adds TLS on top, so
+// the plain listener can be handed off to another process.
+func (s *Server) listen() (listener net.Listener, owned bool, err error) {
+	if listener, owned, err = inheritedListener(); err != nil || listener != nil {
+		return listener, owned, err
+	}
+	if listener, err = activationListener(); err != nil || listener != nil {
+		return listener, false, err
+	}
+
+	address := s.config.SocketPath
+	if scheme, addr := parseAddress(address); scheme == schemeTLS {
+		address = schemeTCP + "://" + addr
+	}
+
+	listener, err = ListenWithConfig(address, ListenConfig{
+		SocketMode:  s.config.SocketMode,
+		SocketGroup: s.config.SocketGroup,
+		LockFile:    s.config.LockFile,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return listener, true, nil
+}
+
+// tlsConfig returns the TLS configuration for the listener, with client
+// certificate verification enabled when TLSClientCAs is set.
+func (s *Server) tlsConfig() *tls.Config {
+	if s.config.TLSConfig == nil || s.config.TLSClientCAs == nil {
+		return s.config.TLSConfig
+	}
+
+	config := s.config.TLSConfig.Clone()
+	config.ClientCAs = s.config.TLSClientCAs
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config
+}
+
+// Addr returns the address the server is listening on, or nil if the server
+// has not started yet. For "tcp://host:0" addresses this reports the actual port.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
 // acceptLoop accepts new connections in a loop.
-func (s *Server) acceptLoop() error {
+func (s *Server) acceptLoop(listener net.Listener) error {
 	for {
 		select {
 		case <-s.shutdownCh:
@@ -176,7 +410,7 @@ func (s *Server) acceptLoop() error {
 		default:
 		}
 
-		conn, err := s.listener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
 			select {
 			case <-s.shutdownCh:
@@ -184,7 +418,7 @@ func (s *Server) acceptLoop() error {
 				return nil
 			default:
 				// Log error but continue accepting
-				s.config.OnError(fmt.Errorf("accept error: %w", err))
+				s.reportError(fmt.Errorf("accept error: %w", err))
 				continue
 			}
 		}
@@ -199,12 +433,34 @@ func (s *Server) acceptLoop() error {
 func (s *Server) handleConnection(netConn net.Conn) {
 	defer s.wg.Done()
 
-	// Create connection
-	conn := newConnection(netConn, s.registry, s.middleware, s)
+	// Complete the TLS handshake up front so client certificates are
+	// verified (and visible to OnConnect) before any request is read
+	if tlsConn, ok := netConn.(*tls.Conn); ok {
+		ctx, cancel := context.WithTimeout(s.ctx, tlsHandshakeTimeout)
+		err := tlsConn.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			s.reportError(fmt.Errorf("tls handshake error: %w", err))
+			netConn.Close()
+			return
+		}
+	}
+
+	s.serveConnection(newConnection(netConn, s.registry, s.middleware, s))
+}
+
+// serveConnection registers conn with the server, serves it until it closes,
+// and runs the connect/disconnect hooks. It is shared by all transports.
+func (s *Server) serveConnection(conn *Connection) {
+	// Close the connection if it does not authenticate in time
+	conn.startAuthTimer()
 
 	// Track connection
 	s.connections.Store(conn, true)
 	s.broadcast.Add(conn)
+	s.trackActive(1)
+	s.metrics.connectionOpened()
+	conn.logger.Debug("client connected", "remote_addr", conn.RemoteAddr())
 
 	// Call OnConnect hook
 	if s.config.OnConnect != nil {
@@ -217,6 +473,9 @@ func (s *Server) handleConnection(netConn net.Conn) {
 	// Cleanup
 	s.connections.Delete(conn)
 	s.broadcast.Remove(conn)
+	s.trackActive(-1)
+	s.metrics.connectionClosed()
+	conn.logger.Debug("client disconnected")
 
 	// Call OnDisconnect hook
 	if s.config.OnDisconnect != nil {
@@ -224,6 +483,49 @@ func (s *Server) handleConnection(netConn net.Conn) {
 	}
 }
 
+// reportError logs an error that isn't tied to a specific request and passes
+// it to the OnError hook.
+func (s *Server) reportError(err error) {
+	s.config.SlogLogger.Error("server error", "error", err)
+	if s.config.OnError != nil {
+		s.config.OnError(err)
+	}
+}
+
+// trackActive updates the active connection count and arms the idle timer
+// when the last connection goes away.
+func (s *Server) trackActive(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active += delta
+	if s.idleTimer == nil {
+		return
+	}
+	if s.active == 0 {
+		s.idleTimer.Reset(s.config.IdleTimeout)
+	} else {
+		s.idleTimer.Stop()
+	}
+}
+
+// stopIfIdle stops the server if no connection arrived while the idle timer
+// was firing.
+func (s *Server) stopIfIdle() {
+	s.mu.Lock()
+	idle := s.active == 0
+	s.mu.Unlock()
+
+	if !idle {
+		return
+	}
+
+	s.config.SlogLogger.Info("no connections, stopping", "idle_timeout", s.config.IdleTimeout)
+	if err := s.Stop(context.Background()); err != nil {
+		s.reportError(fmt.Errorf("idle stop error: %w", err))
+	}
+}
+
 // Stop gracefully stops the server.
 //
 // It closes the listener, waits for all active connections to complete,
@@ -241,13 +543,31 @@ func (s *Server) Stop(ctx context.Context) error {
 		// Signal shutdown
 		close(s.shutdownCh)
 
+		s.mu.Lock()
+		listener := s.listener
+		ownsSocket := s.ownsSocket
+		tokenFile := s.tokenFile
+		if s.idleTimer != nil {
+			s.idleTimer.Stop()
+			s.idleTimer = nil
+		}
+		s.mu.Unlock()
+
 		// Stop accepting new connections
-		if s.listener != nil {
-			if e := s.listener.Close(); e != nil {
+		if listener != nil {
+			if e := listener.Close(); e != nil {
 				err = fmt.Errorf("listener close error: %w", e)
 			}
 		}
 
+		// Refuse new requests on open connections, per the lifecycle config
+		if s.config.Lifecycle != nil {
+			s.connections.Range(func(key, value interface{}) bool {
+				key.(*Connection).SetState(StateShuttingDown)
+				return true
+			})
+		}
+
 		// Wait for connections to finish or timeout
 		done := make(chan struct{})
 		go func() {
@@ -273,20 +593,97 @@ func (s *Server) Stop(ctx context.Context) error {
 		// Cancel server context
 		s.cancel()
 
-		// Clean up socket file (Unix only)
-		if e := CleanupSocket(s.config.SocketPath); e != nil && err == nil {
-			err = fmt.Errorf("socket cleanup error: %w", e)
+		// Clean up socket file (Unix only). A server that never bound its
+		// socket must not remove one that belongs to another instance, and
+		// an activated or handed-off socket belongs to another process.
+		if listener != nil && ownsSocket {
+			if e := CleanupSocket(s.config.SocketPath); e != nil && err == nil {
+				err = fmt.Errorf("socket cleanup error: %w", e)
+			}
+		}
+		if tokenFile != "" {
+			if e := os.Remove(tokenFile); e != nil && !os.IsNotExist(e) && err == nil {
+				err = fmt.Errorf("auth token cleanup error: %w", e)
+			}
 		}
 
-		log.Printf("[JSON-RPC] Server stopped")
+		s.config.SlogLogger.Info("server stopped")
 	})
 
 	return err
 }
 
+// Handoff passes the listening socket to a new process and then stops this
+// server without removing the socket, for upgrades without downtime.
+//
+// cmd is typically the new version of the program. It is started with the
+// socket (and the lock file, with LockFile) in cmd.ExtraFiles, plus
+// environment variables that make its Server.Start adopt them instead of
+// binding the socket again. Once cmd has started, this server stops
+// accepting and waits for its existing connections to finish, like Stop;
+// new clients are accepted by the new process.
+//
+// If cmd fails to start, the server keeps running and the error is returned.
+// Unix only.
+//
+// Example:
+//
+//	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+//	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+//	if err := server.Handoff(ctx, cmd); err != nil {
+//	    log.Printf("upgrade failed: %v", err)
+//	}
+func (s *Server) Handoff(ctx context.Context, cmd *exec.Cmd) error {
+	s.mu.Lock()
+	listener := s.listener
+	owned := s.ownsSocket
+	s.mu.Unlock()
+
+	if listener == nil {
+		return fmt.Errorf("server is not listening")
+	}
+
+	socket, lock, err := listenerFiles(listener)
+	if err != nil {
+		return fmt.Errorf("handoff error: %w", err)
+	}
+	defer socket.Close()
+
+	// ExtraFiles entry i becomes descriptor 3+i in the new process
+	cmd.ExtraFiles = append(cmd.ExtraFiles, socket)
+	socketFD := 2 + len(cmd.ExtraFiles)
+	lockFD := -1
+	if lock != nil {
+		defer lock.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, lock)
+		lockFD = 2 + len(cmd.ExtraFiles)
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, handoffEnv(socketFD, lockFD, owned)...)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start new process: %w", err)
+	}
+	s.config.SlogLogger.Info("listener handed off", "pid", cmd.Process.Pid)
+
+	// The socket now belongs to the new process, which writes its own token
+	s.mu.Lock()
+	s.ownsSocket = false
+	s.tokenFile = ""
+	s.mu.Unlock()
+
+	return s.Stop(ctx)
+}
+
 // Broadcast sends a notification to all connected clients.
 //
-// Returns the number of clients the notification was sent to.
+// Returns the number of clients the notification was queued for. Each
+// connection writes from its own queue, so a slow client does not delay the
+// others until its queue is full; see ServerConfig.WriteQueuePolicy for what
+// happens then.
 //
 // Example:
 //
@@ -298,7 +695,53 @@ func (s *Server) Stop(ctx context.Context) error {
 //
 // Thread-safety: This method is safe to call concurrently.
 func (s *Server) Broadcast(method string, params interface{}) int {
-	return s.broadcast.Broadcast(method, params)
+	return s.finishBroadcast(s.broadcast.BroadcastWithReport(method, params))
+}
+
+// BroadcastWithReport sends a notification to all connected clients and
+// reports the clients it could not be sent to, with the reason.
+//
+// Example:
+//
+//	report := server.BroadcastWithReport("shutdown.pending", nil)
+//	for _, f := range report.Failed {
+//	    log.Printf("client %s missed the notice: %v", f.Conn.RemoteAddr(), f.Err)
+//	}
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) BroadcastWithReport(method string, params interface{}) *BroadcastReport {
+	report := s.broadcast.BroadcastWithReport(method, params)
+	s.finishBroadcast(report)
+	return report
+}
+
+// BroadcastFilter sends a notification to the clients for which include
+// returns true, for example selecting them by RemoteAddr or TLS identity.
+//
+// Example:
+//
+//	server.BroadcastFilter(func(c *jsonrpc.Connection) bool {
+//	    return c.InGroup("admins") || c.InGroup("operators")
+//	}, "alert", alert)
+//
+// Returns the number of clients the notification was queued for.
+//
+// Thread-safety: This method is safe to call concurrently.
+func (s *Server) BroadcastFilter(include func(*Connection) bool, method string, params interface{}) int {
+	return s.finishBroadcast(s.broadcast.broadcastIf(include, method, params))
+}
+
+// finishBroadcast records a broadcast and reports its failures to OnError if
+// ServerConfig.ReportBroadcastErrors is set. It returns the number of
+// connections reached.
+func (s *Server) finishBroadcast(report *BroadcastReport) int {
+	s.metrics.broadcast(report.Sent)
+	if s.config.ReportBroadcastErrors {
+		if err := report.Err(); err != nil {
+			s.reportError(err)
+		}
+	}
+	return report.Sent
 }
 
 // ConnectionCount returns the number of active client connections.
@@ -311,6 +754,15 @@ func (s *Server) Methods() []string {
 	return s.registry.Methods()
 }
 
+// Metrics returns the server's request, connection and traffic metrics.
+//
+// Example:
+//
+//	http.Handle("/metrics", server.Metrics())
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
 // Context returns the server's context.
 // The context is canceled when the server is stopped.
 func (s *Server) Context() context.Context {
@@ -3,6 +3,8 @@ package jsonrpcipc
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -69,14 +71,14 @@ func TestNewServer_DefaultLogger(t *testing.T) {
 
 	server, err := NewServer(ServerConfig{
 		SocketPath: socketPath,
-		Logger:     nil, // Should use default
+		SlogLogger: nil, // Should use default
 	})
 
 	if err != nil {
 		t.Fatalf("NewServer() error: %v", err)
 	}
 
-	if server.config.Logger == nil {
+	if server.config.SlogLogger == nil {
 		t.Error("Default logger not set")
 	}
 }
@@ -586,3 +588,289 @@ func TestServer_Broadcast_WithConnections(t *testing.T) {
 	defer cancel()
 	server.Stop(ctx)
 }
+
+func TestServer_SecondInstanceDoesNotStealSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Socket file test not applicable on Windows")
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "single.sock")
+
+	first, err := NewServer(ServerConfig{SocketPath: socketPath})
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	go first.Start()
+	time.Sleep(50 * time.Millisecond)
+	defer first.Stop(context.Background())
+
+	second, err := NewServer(ServerConfig{SocketPath: socketPath})
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+
+	if err := second.Start(); !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("second Start() error = %v, want ErrAddressInUse", err)
+	}
+	second.Stop(context.Background())
+
+	// The failed instance must not have removed the live socket
+	conn, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("Dial() to first server error: %v", err)
+	}
+	conn.Close()
+}
+
+// startNetworkServer starts a server on address with an "add" method and
+// returns it along with the address clients should dial.
+func startNetworkServer(t *testing.T, config ServerConfig) (*Server, string) {
+	t.Helper()
+
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	server.RegisterFunc("add", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var nums struct {
+			A int `json:"a"`
+			B int `json:"b"`
+		}
+		if err := json.Unmarshal(params, &nums); err != nil {
+			return nil, err
+		}
+		return nums.A + nums.B, nil
+	})
+
+	go server.Start()
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Addr() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Server did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	scheme, _ := parseAddress(config.SocketPath)
+	return server, scheme + "://" + server.Addr().String()
+}
+
+// callAdd sends an "add" request over conn and returns the result.
+func callAdd(t *testing.T, conn net.Conn) (float64, error) {
+	t.Helper()
+
+	codec := NewCodec(conn)
+	if err := codec.WriteJSON(&Request{JSONRPC: "2.0", Method: "add", Params: json.RawMessage(`{"a": 2, "b": 3}`), ID: 1}); err != nil {
+		return 0, err
+	}
+
+	var resp Response
+	if err := codec.ReadJSON(&resp); err != nil {
+		return 0, err
+	}
+	result, _ := resp.Result.(float64)
+	return result, nil
+}
+
+func TestServer_TCP(t *testing.T) {
+	_, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0"})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	defer conn.Close()
+
+	result, err := callAdd(t, conn)
+	if err != nil {
+		t.Fatalf("call error: %v", err)
+	}
+	if result != 5 {
+		t.Errorf("Result = %v, want 5", result)
+	}
+}
+
+func TestServer_SlogLogger(t *testing.T) {
+	logger, buf := newBufferLogger()
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: "tcp://127.0.0.1:0", SlogLogger: logger})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error: %v", addr, err)
+	}
+	if _, err := callAdd(t, conn); err != nil {
+		t.Fatalf("call error: %v", err)
+	}
+	conn.Write([]byte("not json\n"))
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for buf.find(t, "client disconnected") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Disconnect was not logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := server.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	for _, msg := range []string{"server listening", "client connected", "invalid message", "client disconnected", "server stopped"} {
+		if buf.find(t, msg) == nil {
+			t.Errorf("No %q record logged", msg)
+		}
+	}
+	if record := buf.find(t, "client connected"); record != nil && record["conn_id"] == nil {
+		t.Error("client connected record has no conn_id")
+	}
+}
+
+func TestNewServer_TLSRequiresConfig(t *testing.T) {
+	if _, err := NewServer(ServerConfig{SocketPath: "tls://127.0.0.1:0"}); err == nil {
+		t.Error("NewServer() with tls:// and no TLSConfig should return error")
+	}
+}
+
+func TestServer_TLSClientCertificate(t *testing.T) {
+	certs := newTestCerts(t)
+
+	var peerName string
+	connected := make(chan struct{}, 1)
+	_, addr := startNetworkServer(t, ServerConfig{
+		SocketPath:   "tls://127.0.0.1:0",
+		TLSConfig:    certs.serverConfig(),
+		TLSClientCAs: certs.pool,
+		OnConnect: func(conn *Connection) {
+			if state, ok := conn.TLSConnectionState(); ok && len(state.PeerCertificates) > 0 {
+				peerName = state.PeerCertificates[0].Subject.CommonName
+			}
+			connected <- struct{}{}
+		},
+		OnError: func(error) {},
+	})
+
+	// Without a client certificate the handshake is rejected
+	conn, err := DialWithConfig(addr, DialConfig{TLSConfig: certs.clientConfig(false)})
+	if err == nil {
+		if _, err = callAdd(t, conn); err == nil {
+			t.Error("Request without client certificate succeeded, want failure")
+		}
+		conn.Close()
+	}
+
+	// With a client certificate the request goes through
+	conn, err = DialWithConfig(addr, DialConfig{TLSConfig: certs.clientConfig(true)})
+	if err != nil {
+		t.Fatalf("DialWithConfig() error: %v", err)
+	}
+	defer conn.Close()
+
+	result, err := callAdd(t, conn)
+	if err != nil {
+		t.Fatalf("call error: %v", err)
+	}
+	if result != 5 {
+		t.Errorf("Result = %v, want 5", result)
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("OnConnect not called")
+	}
+	if peerName != "test-client" {
+		t.Errorf("Peer certificate CN = %q, want %q", peerName, "test-client")
+	}
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	server, err := NewServer(ServerConfig{
+		SocketPath:  "tcp://127.0.0.1:0",
+		IdleTimeout: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- server.Start() }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Addr() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Server did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// An open connection keeps the server alive past the timeout
+	conn, err := Dial("tcp://" + server.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("Server stopped while a connection was open")
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	conn.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		server.Stop(context.Background())
+		t.Fatal("Server did not stop after becoming idle")
+	}
+}
+
+func TestServer_ReportBroadcastErrors(t *testing.T) {
+	reported := make(chan error, 1)
+	connected := make(chan *Connection, 1)
+	server, addr := startNetworkServer(t, ServerConfig{
+		SocketPath:            "tcp://127.0.0.1:0",
+		ReportBroadcastErrors: true,
+		OnConnect:             func(conn *Connection) { connected <- conn },
+		OnError:               func(err error) { reported <- err },
+	})
+
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	defer client.Close()
+
+	// Closed, but not yet removed from the server
+	conn := <-connected
+	conn.queue.close()
+
+	report := server.BroadcastWithReport("status", nil)
+	if report.Sent != 0 || len(report.Failed) != 1 {
+		t.Fatalf("Report = %+v, want one failure", report)
+	}
+
+	select {
+	case err := <-reported:
+		var bErr *BroadcastError
+		if !errors.As(err, &bErr) || bErr.Method != "status" {
+			t.Errorf("OnError() got %v, want *BroadcastError for status", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Broadcast failure was not reported to OnError")
+	}
+
+	if count := server.BroadcastFilter(func(*Connection) bool { return false }, "status", nil); count != 0 {
+		t.Errorf("BroadcastFilter() count = %d, want 0", count)
+	}
+	select {
+	case err := <-reported:
+		t.Errorf("OnError() got %v for a broadcast without failures", err)
+	default:
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net"
 	"sync"
+	"testing"
 	"time"
 )
 
@@ -82,6 +83,32 @@ func (m *mockConn) SetDeadline(t time.Time) error      { return nil }
 func (m *mockConn) SetReadDeadline(t time.Time) error  { return nil }
 func (m *mockConn) SetWriteDeadline(t time.Time) error { return nil }
 
+// newTestConnection returns a server connection over a mock connection pair,
+// for tests that feed it messages with handleMessage. register adds the
+// server's handlers; config.SocketPath defaults to an unused TCP address.
+func newTestConnection(t *testing.T, config ServerConfig, register func(server *Server)) *Connection {
+	t.Helper()
+
+	conn1, conn2 := newMockConnPair()
+	t.Cleanup(func() {
+		conn1.Close()
+		conn2.Close()
+	})
+
+	if config.SocketPath == "" {
+		config.SocketPath = "tcp://127.0.0.1:0"
+	}
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	if register != nil {
+		register(server)
+	}
+
+	return newConnection(conn1, server.registry, nil, server)
+}
+
 // compareIDs compares two ID values, accounting for JSON number conversion
 func compareIDs(a, b interface{}) bool {
 	// Handle nil
@@ -0,0 +1,142 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"strings"
+)
+
+// Meta keys of the W3C Trace Context (https://www.w3.org/TR/trace-context/).
+const (
+	MetaTraceParent = "traceparent"
+	MetaTraceState  = "tracestate"
+)
+
+// TraceContext is a W3C trace context carried in a message's "meta" member:
+//
+//	{"jsonrpc":"2.0","method":"build","id":1,
+//	 "meta":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+//
+// Requests with a valid traceparent have their trace context in the handler
+// context (see TraceContextFromContext), and Connection.NotifyContext copies
+// it onto notifications so the client can link them to the trace.
+//
+// No other message carries it: notifications sent with Notify, Broadcast,
+// BroadcastFilter or Publish have no trace context, and the server never
+// sends requests to clients. Client notifications are not dispatched to
+// handlers, so their traceparent is ignored.
+type TraceContext struct {
+	TraceParent string // "version-traceid-parentid-flags"
+	TraceState  string // Vendor-specific key/value list (optional)
+}
+
+// IsValid reports whether TraceParent is a well-formed traceparent header.
+func (tc TraceContext) IsValid() bool {
+	return validTraceParent(tc.TraceParent)
+}
+
+// TraceContextFromContext retrieves the trace context of the current request.
+// The boolean is false if the request carried no valid traceparent.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(contextKeyTrace).(TraceContext)
+	return tc, ok
+}
+
+// WithTraceContext adds a trace context to the context. A Tracer calls it
+// with the traceparent of the span it started, so notifications sent by the
+// handler are attributed to that span.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, contextKeyTrace, tc)
+}
+
+// Tracer creates a span for each request handled by the server. It is the
+// hook for tracing libraries such as OpenTelemetry, so this package does not
+// depend on them.
+//
+// Start is called before the middleware chain and handler run, with the
+// request's trace context (if any) available through TraceContextFromContext.
+// It returns the context for the handler, typically carrying the new span,
+// and a function that is called with the handler's error when it returns.
+//
+// Example (OpenTelemetry):
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, method string) (context.Context, func(error)) {
+//	    if tc, ok := jsonrpc.TraceContextFromContext(ctx); ok {
+//	        carrier := propagation.MapCarrier{"traceparent": tc.TraceParent, "tracestate": tc.TraceState}
+//	        ctx = propagation.TraceContext{}.Extract(ctx, carrier)
+//	    }
+//	    ctx, span := t.tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+//
+//	    carrier := propagation.MapCarrier{}
+//	    propagation.TraceContext{}.Inject(ctx, carrier)
+//	    ctx = jsonrpc.WithTraceContext(ctx, jsonrpc.TraceContext{
+//	        TraceParent: carrier["traceparent"],
+//	        TraceState:  carrier["tracestate"],
+//	    })
+//
+//	    return ctx, func(err error) {
+//	        if err != nil {
+//	            span.RecordError(err)
+//	            span.SetStatus(codes.Error, err.Error())
+//	        }
+//	        span.End()
+//	    }
+//	}
+type Tracer interface {
+	Start(ctx context.Context, method string) (context.Context, func(err error))
+}
+
+// traceContextFromMeta extracts a valid trace context from message metadata.
+func traceContextFromMeta(meta Meta) (TraceContext, bool) {
+	parent, _ := meta[MetaTraceParent].(string)
+	if !validTraceParent(parent) {
+		return TraceContext{}, false
+	}
+	state, _ := meta[MetaTraceState].(string)
+	return TraceContext{TraceParent: parent, TraceState: state}, true
+}
+
+// traceMeta returns metadata carrying the trace context of ctx, or nil if
+// ctx has none.
+func traceMeta(ctx context.Context) Meta {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok || !tc.IsValid() {
+		return nil
+	}
+	meta := Meta{MetaTraceParent: tc.TraceParent}
+	if tc.TraceState != "" {
+		meta[MetaTraceState] = tc.TraceState
+	}
+	return meta
+}
+
+// validTraceParent checks the traceparent format:
+// 2 hex version, 32 hex trace ID, 16 hex parent ID and 2 hex flags, with
+// all-zero IDs and version ff invalid. Versions after 00 may append fields.
+func validTraceParent(s string) bool {
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return false
+	}
+	version, traceID, parentID, flags := s[0:2], s[3:35], s[36:52], s[53:55]
+	if version == "ff" || (version == "00" && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return false
+	}
+	for _, field := range []string{version, traceID, parentID, flags} {
+		if !isLowerHex(field) {
+			return false
+		}
+	}
+	return strings.Trim(traceID, "0") != "" && strings.Trim(parentID, "0") != ""
+}
+
+// isLowerHex reports whether s consists of lowercase hex digits.
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
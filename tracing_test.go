@@ -0,0 +1,193 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestValidTraceParent(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"valid", testTraceParent, true},
+		{"empty", "", false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"zero parent id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"version 00 with extra field", testTraceParent + "-00", false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-00", true},
+		{"bad separator", "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"short", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validTraceParent(tt.value); got != tt.want {
+				t.Errorf("validTraceParent(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceContextFromMeta(t *testing.T) {
+	tc, ok := traceContextFromMeta(Meta{MetaTraceParent: testTraceParent, MetaTraceState: "vendor=1"})
+	if !ok {
+		t.Fatal("traceContextFromMeta() ok = false, want true")
+	}
+	if tc.TraceParent != testTraceParent || tc.TraceState != "vendor=1" {
+		t.Errorf("traceContextFromMeta() = %+v", tc)
+	}
+
+	if _, ok := traceContextFromMeta(Meta{MetaTraceParent: "garbage", MetaTraceState: "vendor=1"}); ok {
+		t.Error("traceContextFromMeta() accepted an invalid traceparent")
+	}
+	if _, ok := traceContextFromMeta(nil); ok {
+		t.Error("traceContextFromMeta(nil) ok = true, want false")
+	}
+}
+
+func TestTraceMeta(t *testing.T) {
+	if meta := traceMeta(context.Background()); meta != nil {
+		t.Errorf("traceMeta() without trace context = %v, want nil", meta)
+	}
+
+	ctx := WithTraceContext(context.Background(), TraceContext{TraceParent: testTraceParent})
+	meta := traceMeta(ctx)
+	if meta[MetaTraceParent] != testTraceParent {
+		t.Errorf("traceparent = %v, want %s", meta[MetaTraceParent], testTraceParent)
+	}
+	if _, ok := meta[MetaTraceState]; ok {
+		t.Error("Empty tracestate should be omitted")
+	}
+}
+
+func TestConnection_TraceContextPropagation(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	var got TraceContext
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("build", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		got, _ = TraceContextFromContext(ctx)
+		ConnectionFromContext(ctx).NotifyContext(ctx, "build.progress", 50)
+		return "ok", nil
+	})
+
+	connection := newConnection(conn1, registry, nil, nil)
+
+	msgCh := make(chan Message, 2)
+	go func() {
+		codec := NewCodec(conn2)
+		for i := 0; i < 2; i++ {
+			var msg Message
+			if err := codec.ReadJSON(&msg); err != nil {
+				return
+			}
+			msgCh <- msg
+		}
+	}()
+
+	connection.handleRequest(&Request{
+		JSONRPC: "2.0",
+		Method:  "build",
+		ID:      1,
+		Meta:    Meta{MetaTraceParent: testTraceParent, MetaTraceState: "vendor=1"},
+	})
+
+	if got.TraceParent != testTraceParent || got.TraceState != "vendor=1" {
+		t.Errorf("TraceContextFromContext() = %+v", got)
+	}
+
+	select {
+	case msg := <-msgCh:
+		if msg.Method != "build.progress" {
+			t.Fatalf("First message = %+v, want build.progress notification", msg)
+		}
+		if msg.Meta[MetaTraceParent] != testTraceParent || msg.Meta[MetaTraceState] != "vendor=1" {
+			t.Errorf("Notification meta = %v, want the request's trace context", msg.Meta)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for notification")
+	}
+}
+
+// recordingTracer is a Tracer that records the spans it starts.
+type recordingTracer struct {
+	methods []string
+	errs    []error
+}
+
+func (r *recordingTracer) Start(ctx context.Context, method string) (context.Context, func(error)) {
+	r.methods = append(r.methods, method)
+	ctx = WithTraceContext(ctx, TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-1111111111111111-01"})
+	return ctx, func(err error) { r.errs = append(r.errs, err) }
+}
+
+func TestConnection_Tracer(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn1.Close()
+	defer conn2.Close()
+	go func() {
+		codec := NewCodec(conn2)
+		for {
+			if _, err := codec.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	tracer := &recordingTracer{}
+	server, err := NewServer(ServerConfig{SocketPath: "tcp://127.0.0.1:0", Tracer: tracer})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	var spanParent string
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("fail", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		tc, _ := TraceContextFromContext(ctx)
+		spanParent = tc.TraceParent
+		return nil, errors.New("boom")
+	})
+
+	connection := newConnection(conn1, registry, nil, server)
+	connection.handleRequest(&Request{JSONRPC: "2.0", Method: "fail", ID: 1, Meta: Meta{MetaTraceParent: testTraceParent}})
+
+	if len(tracer.methods) != 1 || tracer.methods[0] != "fail" {
+		t.Fatalf("Tracer spans = %v, want [fail]", tracer.methods)
+	}
+	if len(tracer.errs) != 1 || tracer.errs[0] == nil {
+		t.Errorf("Tracer finish errors = %v, want the handler error", tracer.errs)
+	}
+	if spanParent != "00-4bf92f3577b34da6a3ce929d0e0e4736-1111111111111111-01" {
+		t.Errorf("Handler trace context = %q, want the tracer's span", spanParent)
+	}
+}
+
+func TestConnection_TracedClientNotificationIgnored(t *testing.T) {
+	tracer := &recordingTracer{}
+	called := false
+	connection := newTestConnection(t, ServerConfig{Tracer: tracer}, func(server *Server) {
+		server.RegisterFunc("saved", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+	})
+
+	reply, err := connection.handleMessage([]byte(`{"jsonrpc":"2.0","method":"saved","meta":{"traceparent":"` + testTraceParent + `"}}`))
+	if err != nil || reply != nil {
+		t.Fatalf("handleMessage() = %#v, %v, want no reply", reply, err)
+	}
+	if called || len(tracer.methods) != 0 {
+		t.Errorf("Notification reached the handler (%v) or tracer (%v)", called, tracer.methods)
+	}
+}
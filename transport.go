@@ -1,15 +1,194 @@
 package jsonrpcipc
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"runtime"
+	"strings"
+	"syscall"
 )
 
-// Listen and Dial are implemented in platform-specific files:
+// Listen and Dial accept either a plain socket path or a URL-style address:
+//   - "myapp", "/tmp/myapp.sock", "unix:///tmp/myapp.sock" - Unix socket / named pipe
+//   - "tcp://127.0.0.1:7000" - plain TCP
+//   - "tls://127.0.0.1:7000" - TCP with TLS
+//
+// Local (Unix socket / named pipe) transports are implemented in platform-specific files:
 // - transport_windows.go (for Windows)
 // - transport_unix.go (for Unix/Linux/Mac)
 
+// Address schemes understood by Listen and Dial.
+const (
+	schemeUnix = "unix"
+	schemeTCP  = "tcp"
+	schemeTLS  = "tls"
+)
+
+// ErrInsecureSocketDir is returned by Listen when the directory that would hold
+// the socket can be modified by other users.
+var ErrInsecureSocketDir = errors.New("insecure socket directory")
+
+// ErrAddressInUse is returned by Listen when another server is already
+// listening on the socket path, or holds its lock file.
+var ErrAddressInUse = errors.New("address already in use")
+
+// ListenConfig holds optional settings for ListenWithConfig.
+type ListenConfig struct {
+	// SocketMode sets the permission bits of the socket file. The socket is
+	// bound in a private directory and moved into place once its mode and
+	// group are set, so other users cannot connect before. Zero leaves the
+	// mode determined by the process umask.
+	//
+	// Bare socket names live in $XDG_RUNTIME_DIR or a 0700 directory, which
+	// other users cannot enter whatever the socket's mode; use an explicit
+	// path to share a socket.
+	//
+	// Unix only; ignored on Windows.
+	SocketMode os.FileMode
+
+	// SocketGroup sets the group ownership of the socket file.
+	// It can be a group name ("devs") or a numeric GID ("1001").
+	// Empty leaves the group unchanged. Like SocketMode, it has no effect for
+	// other users on sockets in a 0700 directory.
+	//
+	// Unix only; ignored on Windows.
+	SocketGroup string
+
+	// LockFile enables an exclusive lock file ({socket}.lock) that is held for
+	// the lifetime of the listener. It guarantees a single instance per socket
+	// path even when two servers start at the same moment.
+	//
+	// Unix only; on Windows a named pipe can only have one server anyway.
+	LockFile bool
+
+	// TLSConfig is the server TLS configuration for "tls://" addresses.
+	// It must contain at least one certificate.
+	TLSConfig *tls.Config
+}
+
+// DialConfig holds optional settings for DialWithConfig.
+type DialConfig struct {
+	// TLSConfig is the client TLS configuration for "tls://" addresses.
+	// If nil, the system roots are used and the server name is taken from the address.
+	TLSConfig *tls.Config
+}
+
+// parseAddress splits a URL-style address into its scheme and the address
+// understood by the underlying transport. Addresses without a scheme are
+// local socket paths.
+//
+// Examples:
+//   - "unix:///tmp/myapp.sock" -> "unix", "/tmp/myapp.sock"
+//   - "tcp://127.0.0.1:7000" -> "tcp", "127.0.0.1:7000"
+//   - "myapp" -> "unix", "myapp"
+func parseAddress(address string) (scheme, addr string) {
+	for _, s := range []string{schemeUnix, schemeTCP, schemeTLS} {
+		if prefix := s + "://"; strings.HasPrefix(address, prefix) {
+			return s, strings.TrimPrefix(address, prefix)
+		}
+	}
+	return schemeUnix, address
+}
+
+// isNetworkAddress reports whether address uses a TCP-based transport.
+func isNetworkAddress(address string) bool {
+	scheme, _ := parseAddress(address)
+	return scheme != schemeUnix
+}
+
+// Listen creates a listener with the default ListenConfig.
+func Listen(socketPath string) (net.Listener, error) {
+	return ListenWithConfig(socketPath, ListenConfig{})
+}
+
+// ListenWithConfig creates a listener for a socket path or URL-style address.
+//
+// Unix socket and named pipe addresses are handled by the platform-specific
+// transport; "tcp://" and "tls://" addresses listen on TCP.
+func ListenWithConfig(address string, config ListenConfig) (net.Listener, error) {
+	scheme, addr := parseAddress(address)
+
+	switch scheme {
+	case schemeTCP:
+		return listenTCP(addr, nil)
+	case schemeTLS:
+		if config.TLSConfig == nil {
+			return nil, fmt.Errorf("TLSConfig is required to listen on %s", address)
+		}
+		return listenTCP(addr, config.TLSConfig)
+	default:
+		return listenLocal(addr, config)
+	}
+}
+
+// listenTCP listens on a TCP address, wrapping the listener in TLS if tlsConfig is set.
+func listenTCP(addr string, tlsConfig *tls.Config) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			return nil, fmt.Errorf("%w: %s", ErrAddressInUse, addr)
+		}
+		return nil, fmt.Errorf("failed to create listener on %s: %w", addr, err)
+	}
+
+	if tlsConfig != nil {
+		return tls.NewListener(listener, tlsConfig), nil
+	}
+	return listener, nil
+}
+
+// Dial connects to a server with the default DialConfig.
+func Dial(socketPath string) (net.Conn, error) {
+	return DialWithConfig(socketPath, DialConfig{})
+}
+
+// DialWithConfig connects to a socket path or URL-style address.
+func DialWithConfig(address string, config DialConfig) (net.Conn, error) {
+	scheme, addr := parseAddress(address)
+
+	switch scheme {
+	case schemeTCP:
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		return conn, nil
+	case schemeTLS:
+		conn, err := tls.Dial("tcp", addr, config.TLSConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		return conn, nil
+	default:
+		return dialLocal(addr)
+	}
+}
+
+// CleanupSocket removes the Unix socket file.
+// This should be called when the server shuts down to clean up resources.
+// It is a no-op for TCP addresses and Windows named pipes.
+func CleanupSocket(socketPath string) error {
+	scheme, addr := parseAddress(socketPath)
+	if scheme != schemeUnix {
+		return nil
+	}
+	return cleanupLocal(addr)
+}
+
+// GetSocketPath returns the actual socket path that will be used by Listen/Dial.
+// This is useful for logging or displaying the socket path to users.
+// TCP addresses are returned unchanged.
+func GetSocketPath(socketPath string) string {
+	scheme, addr := parseAddress(socketPath)
+	if scheme != schemeUnix {
+		return socketPath
+	}
+	return localSocketPath(addr)
+}
+
 // removeSocketFile deletes stale socket files before binding.
 // Binding to an existing socket file will fail, so cleanup is required.
 func removeSocketFile(path string) error {
@@ -31,8 +210,6 @@ func removeSocketFile(path string) error {
 	return nil
 }
 
-// CleanupSocket, GetSocketPath are implemented in platform-specific files
-
 // IsWindows returns true if running on Windows.
 func IsWindows() bool {
 	return runtime.GOOS == "windows"
@@ -0,0 +1,145 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// abstractSocketName returns a unique abstract socket name for a test.
+func abstractSocketName(t *testing.T) string {
+	return fmt.Sprintf("@ipc-jsonrpc-test-%s-%d-%d", t.Name(), os.Getpid(), time.Now().UnixNano())
+}
+
+func TestNormalizeSocketPath_Abstract(t *testing.T) {
+	if got := normalizeSocketPath("@myapp"); got != "@myapp" {
+		t.Errorf("normalizeSocketPath(%q) = %q, want %q", "@myapp", got, "@myapp")
+	}
+	if got := GetSocketPath("@myapp"); got != "@myapp" {
+		t.Errorf("GetSocketPath(%q) = %q, want %q", "@myapp", got, "@myapp")
+	}
+}
+
+func TestListen_Dial_AbstractSocket(t *testing.T) {
+	name := abstractSocketName(t)
+
+	listener, err := Listen(name)
+	if err != nil {
+		t.Fatalf("Listen(%q) error = %v", name, err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			accepted <- err
+			return
+		}
+		defer conn.Close()
+		_, err = conn.Write([]byte("hello"))
+		accepted <- err
+	}()
+
+	conn, err := Dial(name)
+	if err != nil {
+		t.Fatalf("Dial(%q) error = %v", name, err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Read error = %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("Received %q, want %q", buf, "hello")
+	}
+
+	if err := <-accepted; err != nil {
+		t.Errorf("Server error = %v", err)
+	}
+}
+
+func TestListen_AbstractSocket_NoFile(t *testing.T) {
+	name := abstractSocketName(t)
+
+	listener, err := Listen(name)
+	if err != nil {
+		t.Fatalf("Listen(%q) error = %v", name, err)
+	}
+	defer listener.Close()
+
+	// Nothing must be created on the filesystem, in the cwd or the default dir
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("Abstract socket created a file %q", name)
+	}
+	if _, err := os.Stat(defaultSocketDir() + "/" + name + ".sock"); !os.IsNotExist(err) {
+		t.Error("Abstract socket created a file in the default socket directory")
+	}
+}
+
+func TestListen_AbstractSocket_AddressInUse(t *testing.T) {
+	name := abstractSocketName(t)
+
+	first, err := Listen(name)
+	if err != nil {
+		t.Fatalf("first Listen(%q) error = %v", name, err)
+	}
+	defer first.Close()
+
+	second, err := Listen(name)
+	if err == nil {
+		second.Close()
+		t.Fatal("second Listen() on abstract socket succeeded, want error")
+	}
+	if !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("second Listen() error = %v, want ErrAddressInUse", err)
+	}
+}
+
+func TestListen_AbstractSocket_ReleasedOnClose(t *testing.T) {
+	name := abstractSocketName(t)
+
+	listener, err := Listen(name)
+	if err != nil {
+		t.Fatalf("Listen(%q) error = %v", name, err)
+	}
+	listener.Close()
+
+	if err := CleanupSocket(name); err != nil {
+		t.Errorf("CleanupSocket(%q) error = %v, want nil", name, err)
+	}
+
+	// The name is free again as soon as the listener is closed
+	listener, err = Listen(name)
+	if err != nil {
+		t.Fatalf("Listen(%q) after close error = %v", name, err)
+	}
+	listener.Close()
+}
+
+func TestServer_AbstractSocket(t *testing.T) {
+	name := abstractSocketName(t)
+
+	server, err := NewServer(ServerConfig{SocketPath: name})
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+
+	go server.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	conn, err := Dial(name)
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	conn.Close()
+
+	if err := server.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error: %v", err)
+	}
+}
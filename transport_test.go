@@ -1,7 +1,14 @@
 package jsonrpcipc
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"io"
+	"math/big"
 	"net"
 	"os"
 	"path/filepath"
@@ -45,13 +52,13 @@ func TestGetSocketPath(t *testing.T) {
 			name:        "simple name",
 			path:        "myapp",
 			wantWindows: `\\.\pipe\myapp`,
-			wantUnix:    "/tmp/myapp.sock",
+			wantUnix:    "/run/user/1000/myapp.sock",
 		},
 		{
 			name:        "path with sock extension",
 			path:        "myapp.sock",
 			wantWindows: `\\.\pipe\myapp.sock`,
-			wantUnix:    "/tmp/myapp.sock",
+			wantUnix:    "/run/user/1000/myapp.sock",
 		},
 		{
 			name:        "absolute unix path",
@@ -61,6 +68,8 @@ func TestGetSocketPath(t *testing.T) {
 		},
 	}
 
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := GetSocketPath(tt.path)
@@ -361,11 +370,12 @@ func TestListen_RemoveStaleSocket(t *testing.T) {
 	socketPath := filepath.Join(tmpDir, "stale.sock")
 
 	// Create a stale socket file
-	file, err := os.Create(socketPath)
+	stale, err := net.Listen("unix", socketPath)
 	if err != nil {
 		t.Fatalf("Failed to create stale socket: %v", err)
 	}
-	file.Close()
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
 
 	// Listen should remove the stale socket and create a new listener
 	listener, err := Listen(socketPath)
@@ -383,3 +393,223 @@ func TestListen_RemoveStaleSocket(t *testing.T) {
 		conn.Close()
 	}
 }
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address    string
+		wantScheme string
+		wantAddr   string
+	}{
+		{"myapp", "unix", "myapp"},
+		{"/tmp/myapp.sock", "unix", "/tmp/myapp.sock"},
+		{"unix:///tmp/myapp.sock", "unix", "/tmp/myapp.sock"},
+		{"tcp://127.0.0.1:7000", "tcp", "127.0.0.1:7000"},
+		{"tls://localhost:7443", "tls", "localhost:7443"},
+		{`C:\sockets\myapp.sock`, "unix", `C:\sockets\myapp.sock`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			scheme, addr := parseAddress(tt.address)
+			if scheme != tt.wantScheme || addr != tt.wantAddr {
+				t.Errorf("parseAddress(%q) = (%q, %q), want (%q, %q)",
+					tt.address, scheme, addr, tt.wantScheme, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestGetSocketPath_NetworkAddress(t *testing.T) {
+	if got := GetSocketPath("tcp://127.0.0.1:7000"); got != "tcp://127.0.0.1:7000" {
+		t.Errorf("GetSocketPath() = %q, want address unchanged", got)
+	}
+	if err := CleanupSocket("tcp://127.0.0.1:7000"); err != nil {
+		t.Errorf("CleanupSocket() for TCP address error = %v, want nil", err)
+	}
+}
+
+func TestListen_Dial_UnixScheme(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix socket path test not applicable on Windows")
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "scheme.sock")
+
+	listener, err := Listen("unix://" + socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Errorf("Socket file not created at %s: %v", socketPath, err)
+	}
+
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	conn, err := Dial("unix://" + socketPath)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	conn.Close()
+}
+
+// echoOnce accepts a single connection and echoes one line back.
+func echoOnce(t *testing.T, listener net.Listener) {
+	t.Helper()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		codec := NewCodec(conn)
+		msg, err := codec.ReadMessage()
+		if err != nil {
+			return
+		}
+		codec.WriteMessage(msg)
+	}()
+}
+
+// roundTrip writes a line to conn and returns the echoed line.
+func roundTrip(t *testing.T, conn net.Conn, msg string) string {
+	t.Helper()
+	codec := NewCodec(conn)
+	if err := codec.WriteMessage([]byte(msg)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	reply, err := codec.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	return string(reply)
+}
+
+func TestListen_Dial_TCP(t *testing.T) {
+	listener, err := Listen("tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	echoOnce(t, listener)
+
+	conn, err := Dial("tcp://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if got := roundTrip(t, conn, "ping"); got != "ping" {
+		t.Errorf("Echo = %q, want %q", got, "ping")
+	}
+}
+
+func TestListen_TLS_RequiresConfig(t *testing.T) {
+	listener, err := Listen("tls://127.0.0.1:0")
+	if err == nil {
+		listener.Close()
+		t.Fatal("Listen() on tls:// without TLSConfig succeeded, want error")
+	}
+}
+
+func TestListen_Dial_TLS(t *testing.T) {
+	certs := newTestCerts(t)
+
+	listener, err := ListenWithConfig("tls://127.0.0.1:0", ListenConfig{
+		TLSConfig: certs.serverConfig(),
+	})
+	if err != nil {
+		t.Fatalf("ListenWithConfig() error = %v", err)
+	}
+	defer listener.Close()
+	echoOnce(t, listener)
+
+	conn, err := DialWithConfig("tls://"+listener.Addr().String(), DialConfig{
+		TLSConfig: certs.clientConfig(false),
+	})
+	if err != nil {
+		t.Fatalf("DialWithConfig() error = %v", err)
+	}
+	defer conn.Close()
+
+	if got := roundTrip(t, conn, "secure"); got != "secure" {
+		t.Errorf("Echo = %q, want %q", got, "secure")
+	}
+}
+
+// testCerts is a throwaway CA with a server and a client certificate.
+type testCerts struct {
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func newTestCerts(t *testing.T) *testCerts {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate(ca) error = %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("CreateCertificate(%s) error = %v", name, err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	return &testCerts{
+		pool:   pool,
+		server: issue(2, "test-server", x509.ExtKeyUsageServerAuth),
+		client: issue(3, "test-client", x509.ExtKeyUsageClientAuth),
+	}
+}
+
+func (c *testCerts) serverConfig() *tls.Config {
+	return &tls.Config{Certificates: []tls.Certificate{c.server}}
+}
+
+func (c *testCerts) clientConfig(withCert bool) *tls.Config {
+	config := &tls.Config{RootCAs: c.pool}
+	if withCert {
+		config.Certificates = []tls.Certificate{c.client}
+	}
+	return config
+}
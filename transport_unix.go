@@ -3,55 +3,345 @@
 package jsonrpcipc
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// isAbstractSocket reports whether socketPath names a Linux abstract socket.
+// Abstract sockets are written with a leading '@' and live outside the
+// filesystem, so there is no socket file to secure or clean up.
+func isAbstractSocket(socketPath string) bool {
+	return strings.HasPrefix(socketPath, "@")
+}
+
+// abstractSocketsSupported reports whether the platform supports abstract sockets.
+func abstractSocketsSupported() bool {
+	return runtime.GOOS == "linux" || runtime.GOOS == "android"
+}
+
+// defaultSocketDir returns the directory used for bare socket names.
+//
+// $XDG_RUNTIME_DIR is preferred because it is private to the user. When it is
+// not set, a per-user directory under /tmp is used instead.
+func defaultSocketDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join("/tmp", fmt.Sprintf("ipc-jsonrpc-%d", os.Getuid()))
+}
+
 // normalizeSocketPath converts simple socket names to full Unix socket paths
-// Examples:
-//   - "myapp" -> "/tmp/myapp.sock"
+// Examples (with XDG_RUNTIME_DIR=/run/user/1000):
+//   - "myapp" -> "/run/user/1000/myapp.sock"
+//   - "myapp.sock" -> "/run/user/1000/myapp.sock"
 //   - "/tmp/myapp.sock" -> "/tmp/myapp.sock" (unchanged)
 //   - "./myapp.sock" -> "./myapp.sock" (unchanged)
+//   - "@myapp" -> "@myapp" (unchanged, Linux abstract socket)
+//
+// Without XDG_RUNTIME_DIR, simple names are placed in /tmp/ipc-jsonrpc-{uid}/.
 func normalizeSocketPath(socketPath string) string {
+	// Abstract socket names ("@myapp") have no filesystem location
+	if isAbstractSocket(socketPath) {
+		return socketPath
+	}
+
 	// If it's already an absolute or relative path with directory separator, keep it
 	if strings.Contains(socketPath, "/") {
 		return socketPath
 	}
 
-	// If it already has .sock extension, just prepend /tmp/
+	// If it already has .sock extension, just prepend the socket directory
 	if strings.HasSuffix(socketPath, ".sock") {
-		return filepath.Join("/tmp", socketPath)
+		return filepath.Join(defaultSocketDir(), socketPath)
+	}
+
+	// Simple name - convert to {dir}/{name}.sock
+	return filepath.Join(defaultSocketDir(), socketPath+".sock")
+}
+
+// prepareSocketDir makes sure the directory that will hold the socket exists
+// and cannot be tampered with by other users.
+//
+// Missing directories are created with mode 0700. An existing directory is
+// rejected if it is owned by another (non-root) user, or if it is writable by
+// group or others without the sticky bit set (as /tmp has).
+func prepareSocketDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory %s: %w", dir, err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat socket directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+
+	mode := info.Mode()
+	if mode.Perm()&0022 != 0 && mode&os.ModeSticky == 0 {
+		return fmt.Errorf("%w: %s is writable by other users (mode %v)", ErrInsecureSocketDir, dir, mode.Perm())
+	}
+
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if int(st.Uid) != os.Getuid() && st.Uid != 0 {
+			return fmt.Errorf("%w: %s is owned by uid %d", ErrInsecureSocketDir, dir, st.Uid)
+		}
+	}
+
+	return nil
+}
+
+// lookupGroupID resolves a group name or numeric GID string to a GID.
+func lookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// applySocketPermissions sets the mode and group of a freshly bound socket.
+func applySocketPermissions(socketPath string, config ListenConfig) error {
+	if config.SocketGroup != "" {
+		gid, err := lookupGroupID(config.SocketGroup)
+		if err != nil {
+			return fmt.Errorf("failed to resolve socket group %q: %w", config.SocketGroup, err)
+		}
+		if err := os.Chown(socketPath, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %w", err)
+		}
+	}
+
+	if config.SocketMode != 0 {
+		if err := os.Chmod(socketPath, config.SocketMode.Perm()); err != nil {
+			return fmt.Errorf("failed to set socket mode: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// unixListener is a socket bound under a temporary name and then moved to
+// path. Like a *net.UnixListener bound at path, it reports path as its
+// address and removes it on Close.
+type unixListener struct {
+	*net.UnixListener
+	path string
+	keep atomic.Bool // Set once the socket is handed off to another process
+}
+
+// Addr returns the socket's final address.
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close closes the listener and removes the socket file.
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	if err == nil && !l.keep.Load() {
+		os.Remove(l.path)
+	}
+	return err
+}
+
+// bindPrivate binds a socket at socketPath that no other user can connect to
+// before its permissions are applied. The socket is bound in a new 0700
+// directory next to socketPath, given its mode and group there, and then
+// linked into place. The process umask is left alone, since changing it
+// would affect files created concurrently by other goroutines.
+func bindPrivate(socketPath string, config ListenConfig) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(socketPath), ".sock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listener on %s: %w", socketPath, err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "s")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listener on %s: %w", socketPath, err)
+	}
+	listener.SetUnlinkOnClose(false)
+
+	if err := applySocketPermissions(tmpPath, config); err != nil {
+		listener.Close()
+		return nil, err
 	}
 
-	// Simple name - convert to /tmp/{name}.sock
-	return filepath.Join("/tmp", socketPath+".sock")
+	// Unlike rename, link fails instead of replacing a socket another
+	// server bound in the meantime
+	if err := os.Link(tmpPath, socketPath); err != nil {
+		listener.Close()
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s", ErrAddressInUse, socketPath)
+		}
+		return nil, fmt.Errorf("failed to create listener on %s: %w", socketPath, err)
+	}
+
+	return &unixListener{UnixListener: listener, path: socketPath}, nil
+}
+
+// lockedListener holds the single-instance lock for as long as the
+// listener is open.
+type lockedListener struct {
+	net.Listener
+	lock *os.File
 }
 
-// Listen creates a Unix domain socket listener
-func Listen(socketPath string) (net.Listener, error) {
+// Close closes the listener and releases the lock.
+func (l *lockedListener) Close() error {
+	err := l.Listener.Close()
+	l.lock.Close()
+	return err
+}
+
+// acquireLockFile takes an exclusive, non-blocking flock on path.
+// The lock is released when the returned file is closed (or the process exits).
+func acquireLockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("%w: lock file %s is held by another process", ErrAddressInUse, path)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// removeStaleSocket removes an existing socket file only if no server answers on it.
+// Returns ErrAddressInUse if a live server is listening.
+func removeStaleSocket(socketPath string) error {
+	info, err := os.Lstat(socketPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check existing socket: %w", err)
+	}
+	// Connecting to a regular file is refused too; it must not be removed
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", socketPath)
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%w: a server is already listening on %s", ErrAddressInUse, socketPath)
+	}
+	if errors.Is(err, syscall.ENOENT) {
+		return nil
+	}
+	// A busy server's full backlog (EAGAIN), missing permissions or a timeout
+	// say nothing about whether the socket is stale
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("failed to check existing socket: %w", err)
+	}
+
+	// Nobody is listening - the socket is stale
+	if err := removeSocketFile(socketPath); err != nil {
+		return fmt.Errorf("failed to remove existing socket: %w", err)
+	}
+	return nil
+}
+
+// listenLocal creates a Unix domain socket listener.
+//
+// The parent directory of the socket is created with mode 0700 if needed,
+// and Listen refuses to bind if the directory is writable by other users.
+//
+// If a socket file already exists, Listen first tries to connect to it.
+// A live server results in ErrAddressInUse; only stale sockets are removed.
+func listenLocal(socketPath string, config ListenConfig) (net.Listener, error) {
 	// Normalize socket path for Unix systems
 	socketPath = normalizeSocketPath(socketPath)
 
-	// Remove existing socket file if it exists
-	if err := removeSocketFile(socketPath); err != nil {
-		return nil, fmt.Errorf("failed to remove existing socket: %w", err)
+	if isAbstractSocket(socketPath) {
+		return listenAbstract(socketPath)
+	}
+
+	if err := prepareSocketDir(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+
+	var lock *os.File
+	if config.LockFile {
+		var err error
+		if lock, err = acquireLockFile(socketPath + ".lock"); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := listenUnix(socketPath, config)
+	if err != nil {
+		if lock != nil {
+			lock.Close()
+		}
+		return nil, err
+	}
+
+	if lock != nil {
+		return &lockedListener{Listener: listener, lock: lock}, nil
+	}
+	return listener, nil
+}
+
+// listenAbstract binds a Linux abstract socket.
+// The kernel rejects a second bind to the same name, so no probing or lock
+// file is needed, and permissions do not apply.
+func listenAbstract(socketPath string) (net.Listener, error) {
+	if !abstractSocketsSupported() {
+		return nil, fmt.Errorf("abstract socket %s is not supported on %s", socketPath, runtime.GOOS)
 	}
 
 	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			return nil, fmt.Errorf("%w: %s", ErrAddressInUse, socketPath)
+		}
 		return nil, fmt.Errorf("failed to create listener on %s: %w", socketPath, err)
 	}
-
 	return listener, nil
 }
 
-// Dial creates a Unix domain socket client connection
-func Dial(socketPath string) (net.Conn, error) {
+// listenUnix binds the socket after clearing out a stale one.
+func listenUnix(socketPath string, config ListenConfig) (net.Listener, error) {
+	if err := removeStaleSocket(socketPath); err != nil {
+		return nil, err
+	}
+
+	return bindPrivate(socketPath, config)
+}
+
+// dialLocal creates a Unix domain socket client connection
+func dialLocal(socketPath string) (net.Conn, error) {
 	// Normalize socket path for Unix systems
 	socketPath = normalizeSocketPath(socketPath)
 
+	if isAbstractSocket(socketPath) && !abstractSocketsSupported() {
+		return nil, fmt.Errorf("abstract socket %s is not supported on %s", socketPath, runtime.GOOS)
+	}
+
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", socketPath, err)
@@ -59,14 +349,146 @@ func Dial(socketPath string) (net.Conn, error) {
 	return conn, nil
 }
 
-// CleanupSocket removes the Unix socket file.
-// This should be called when the server shuts down to clean up resources.
-func CleanupSocket(socketPath string) error {
+// cleanupLocal removes the Unix socket file.
+// Abstract sockets have no file and are released by the kernel on close.
+func cleanupLocal(socketPath string) error {
+	if isAbstractSocket(socketPath) {
+		return nil
+	}
 	return removeSocketFile(normalizeSocketPath(socketPath))
 }
 
-// GetSocketPath returns the actual socket path that will be used by Listen/Dial.
-// This is useful for logging or displaying the socket path to users.
-func GetSocketPath(socketPath string) string {
+// localSocketPath returns the actual socket path that will be used by Listen/Dial.
+func localSocketPath(socketPath string) string {
 	return normalizeSocketPath(socketPath)
 }
+
+// listenFdsStart is the first file descriptor passed by systemd socket
+// activation (SD_LISTEN_FDS_START). It is a variable so tests can substitute
+// a descriptor of their own.
+var listenFdsStart = 3
+
+// activationListener returns the socket passed by systemd socket activation,
+// or nil if the process was not socket-activated.
+//
+// Activation is detected through LISTEN_PID (which must match this process)
+// and LISTEN_FDS. If several sockets are passed, the first one is used.
+// The variables are cleared so child processes don't try to adopt the sockets.
+func activationListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		syscall.CloseOnExec(fd)
+	}
+
+	// FileListener duplicates the descriptor, so the original can be closed
+	file := os.NewFile(uintptr(listenFdsStart), "systemd-socket")
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt activated socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Environment variables used to pass a listener to a new process (see
+// Server.Handoff). They hold the descriptor numbers in the new process.
+const (
+	handoffListenFDEnv = "IPC_JSONRPC_LISTEN_FD"
+	handoffLockFDEnv   = "IPC_JSONRPC_LOCK_FD"
+	// handoffUnownedEnv is set when the socket belongs to the service manager
+	// (socket activation), so the new process must not remove it either
+	handoffUnownedEnv = "IPC_JSONRPC_SOCKET_UNOWNED"
+)
+
+// listenerFiles returns duplicates of the descriptors behind listener and
+// its lock file (nil without LockFile), for passing to another process.
+// The listener no longer unlinks its socket file on Close, since the socket
+// lives on in the other process.
+func listenerFiles(listener net.Listener) (socket, lock *os.File, err error) {
+	if locked, ok := listener.(*lockedListener); ok {
+		fd, err := syscall.Dup(int(locked.lock.Fd()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to duplicate lock file: %w", err)
+		}
+		lock = os.NewFile(uintptr(fd), locked.lock.Name())
+		listener = locked.Listener
+	}
+
+	switch l := listener.(type) {
+	case *unixListener:
+		l.keep.Store(true)
+		socket, err = l.File()
+	case *net.UnixListener:
+		l.SetUnlinkOnClose(false)
+		socket, err = l.File()
+	case *net.TCPListener:
+		socket, err = l.File()
+	default:
+		err = fmt.Errorf("listener type %T cannot be handed off", listener)
+	}
+	if err != nil {
+		if lock != nil {
+			lock.Close()
+		}
+		return nil, nil, err
+	}
+	return socket, lock, nil
+}
+
+// handoffEnv returns the environment entries that tell the new process
+// which descriptors to adopt. Descriptors are numbered from 3 in the order
+// of exec.Cmd.ExtraFiles.
+func handoffEnv(socketFD, lockFD int, owned bool) []string {
+	env := []string{fmt.Sprintf("%s=%d", handoffListenFDEnv, socketFD)}
+	if lockFD >= 0 {
+		env = append(env, fmt.Sprintf("%s=%d", handoffLockFDEnv, lockFD))
+	}
+	if !owned {
+		env = append(env, handoffUnownedEnv+"=1")
+	}
+	return env
+}
+
+// inheritedListener returns the listener passed by a previous process with
+// Server.Handoff, or nil if there is none. owned reports whether this process
+// is now responsible for removing the socket file.
+func inheritedListener() (listener net.Listener, owned bool, err error) {
+	socketFD, err := strconv.Atoi(os.Getenv(handoffListenFDEnv))
+	if err != nil {
+		return nil, false, nil
+	}
+	lockFD, lockErr := strconv.Atoi(os.Getenv(handoffLockFDEnv))
+	owned = os.Getenv(handoffUnownedEnv) == ""
+
+	os.Unsetenv(handoffListenFDEnv)
+	os.Unsetenv(handoffLockFDEnv)
+	os.Unsetenv(handoffUnownedEnv)
+
+	syscall.CloseOnExec(socketFD)
+	file := os.NewFile(uintptr(socketFD), "inherited-socket")
+	defer file.Close()
+
+	listener, err = net.FileListener(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to adopt inherited socket: %w", err)
+	}
+
+	if lockErr == nil {
+		syscall.CloseOnExec(lockFD)
+		listener = &lockedListener{Listener: listener, lock: os.NewFile(uintptr(lockFD), "inherited-lock")}
+	}
+	return listener, owned, nil
+}
@@ -0,0 +1,598 @@
+//go:build !windows
+
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNormalizeUnixSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "simple name",
+			input: "myapp",
+			want:  "/run/user/1000/myapp.sock",
+		},
+		{
+			name:  "simple name with sock extension",
+			input: "myapp.sock",
+			want:  "/run/user/1000/myapp.sock",
+		},
+		{
+			name:  "absolute path",
+			input: "/tmp/myapp.sock",
+			want:  "/tmp/myapp.sock",
+		},
+		{
+			name:  "relative path with directory",
+			input: "./sockets/myapp.sock",
+			want:  "./sockets/myapp.sock",
+		},
+		{
+			name:  "name with special chars",
+			input: "my-app_123",
+			want:  "/run/user/1000/my-app_123.sock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeSocketPath(tt.input)
+			if got != tt.want {
+				t.Errorf("normalizeSocketPath(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeUnixSocketPath_NoRuntimeDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	want := fmt.Sprintf("/tmp/ipc-jsonrpc-%d/myapp.sock", os.Getuid())
+	if got := normalizeSocketPath("myapp"); got != want {
+		t.Errorf("normalizeSocketPath(%q) = %q, want %q", "myapp", got, want)
+	}
+}
+
+func TestListen_CreatesPrivateParentDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run", "myapp")
+	socketPath := filepath.Join(dir, "test.sock")
+
+	listener, err := Listen(socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Stat(%q) error = %v", dir, err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("Parent directory mode = %v, want 0700", perm)
+	}
+}
+
+func TestListen_RefusesInsecureParentDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	// Chmod explicitly so the umask can't mask the group/other write bits
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+
+	listener, err := Listen(filepath.Join(dir, "test.sock"))
+	if err == nil {
+		listener.Close()
+		t.Fatal("Listen() in world-writable directory succeeded, want error")
+	}
+	if !errors.Is(err, ErrInsecureSocketDir) {
+		t.Errorf("Listen() error = %v, want ErrInsecureSocketDir", err)
+	}
+}
+
+func TestListen_AllowsStickyParentDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sticky")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if err := os.Chmod(dir, 0777|os.ModeSticky); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+
+	listener, err := Listen(filepath.Join(dir, "test.sock"))
+	if err != nil {
+		t.Fatalf("Listen() in sticky directory error = %v", err)
+	}
+	listener.Close()
+}
+
+func TestListenWithConfig_SocketMode(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "mode.sock")
+
+	listener, err := ListenWithConfig(socketPath, ListenConfig{SocketMode: 0600})
+	if err != nil {
+		t.Fatalf("ListenWithConfig() error = %v", err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Socket mode = %v, want 0600", perm)
+	}
+}
+
+func TestListenWithConfig_DefaultSocketMode(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "umask.sock")
+
+	umask := syscall.Umask(0022)
+	defer syscall.Umask(umask)
+
+	listener, err := ListenWithConfig(socketPath, ListenConfig{})
+	if err != nil {
+		t.Fatalf("ListenWithConfig() error = %v", err)
+	}
+	defer listener.Close()
+
+	// The socket gets the mode the umask allows, and the umask is untouched
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0755 {
+		t.Errorf("Socket mode = %v, want 0755", perm)
+	}
+	if got := syscall.Umask(umask); got != 0022 {
+		t.Errorf("Umask after Listen = %#o, want 022", got)
+	}
+}
+
+func TestListenWithConfig_BindsInPlace(t *testing.T) {
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "moved.sock")
+
+	listener, err := ListenWithConfig(socketPath, ListenConfig{SocketMode: 0600})
+	if err != nil {
+		t.Fatalf("ListenWithConfig() error = %v", err)
+	}
+	if got := listener.Addr().String(); got != socketPath {
+		t.Errorf("Addr() = %q, want %q", got, socketPath)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Directory holds %d entries, want only the socket", len(entries))
+	}
+
+	conn, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	conn.Close()
+
+	listener.Close()
+	if _, err := os.Lstat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Socket after Close: %v, want it removed", err)
+	}
+}
+
+func TestListenWithConfig_SocketGroup(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "group.sock")
+	gid := os.Getgid()
+
+	listener, err := ListenWithConfig(socketPath, ListenConfig{
+		SocketMode:  0660,
+		SocketGroup: fmt.Sprint(gid),
+	})
+	if err != nil {
+		t.Fatalf("ListenWithConfig() error = %v", err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0660 {
+		t.Errorf("Socket mode = %v, want 0660", perm)
+	}
+}
+
+func TestListenWithConfig_UnknownGroup(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "badgroup.sock")
+
+	listener, err := ListenWithConfig(socketPath, ListenConfig{
+		SocketGroup: "no-such-group-ipc-jsonrpc",
+	})
+	if err == nil {
+		listener.Close()
+		t.Fatal("ListenWithConfig() with unknown group succeeded, want error")
+	}
+
+	// The half-created socket must not be left behind
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Error("Socket file was left behind after failed ListenWithConfig()")
+	}
+}
+
+func TestListen_LiveServerReturnsAddressInUse(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "live.sock")
+
+	first, err := Listen(socketPath)
+	if err != nil {
+		t.Fatalf("first Listen() error = %v", err)
+	}
+	defer first.Close()
+
+	go func() {
+		for {
+			conn, err := first.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	second, err := Listen(socketPath)
+	if err == nil {
+		second.Close()
+		t.Fatal("second Listen() on a live socket succeeded, want error")
+	}
+	if !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("second Listen() error = %v, want ErrAddressInUse", err)
+	}
+
+	// The running instance must still be reachable
+	conn, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("Dial() after failed takeover error = %v", err)
+	}
+	conn.Close()
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "stale.sock")
+
+	// Leave a real socket file behind with nobody listening on it
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := Listen(socketPath)
+	if err != nil {
+		t.Fatalf("Listen() over stale socket error = %v", err)
+	}
+	listener.Close()
+}
+
+func TestListen_RefusesNonSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "data.sock")
+	if err := os.WriteFile(socketPath, []byte("data"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if listener, err := Listen(socketPath); err == nil {
+		listener.Close()
+		t.Fatal("Listen() over a regular file succeeded, want error")
+	}
+	if data, err := os.ReadFile(socketPath); err != nil || string(data) != "data" {
+		t.Errorf("Regular file after Listen() = %q, %v, want it untouched", data, err)
+	}
+}
+
+func TestListen_KeepsSocketOnDialError(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Socket permissions do not apply to root")
+	}
+	socketPath := filepath.Join(t.TempDir(), "private.sock")
+
+	// A socket we may not connect to could belong to a live server
+	other, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer other.Close()
+	if err := os.Chmod(socketPath, 0); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+
+	if listener, err := Listen(socketPath); err == nil {
+		listener.Close()
+		t.Fatal("Listen() over an inaccessible socket succeeded, want error")
+	}
+	if _, err := os.Lstat(socketPath); err != nil {
+		t.Errorf("Socket removed after a permission error: %v", err)
+	}
+}
+
+func TestListenWithConfig_LockFile(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "locked.sock")
+
+	first, err := ListenWithConfig(socketPath, ListenConfig{LockFile: true})
+	if err != nil {
+		t.Fatalf("first ListenWithConfig() error = %v", err)
+	}
+
+	if _, err := os.Stat(socketPath + ".lock"); err != nil {
+		t.Errorf("Lock file not created: %v", err)
+	}
+
+	second, err := ListenWithConfig(socketPath, ListenConfig{LockFile: true})
+	if err == nil {
+		second.Close()
+		t.Fatal("second ListenWithConfig() succeeded while lock is held, want error")
+	}
+	if !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("second ListenWithConfig() error = %v, want ErrAddressInUse", err)
+	}
+
+	// Closing the first listener releases the lock
+	first.Close()
+
+	third, err := ListenWithConfig(socketPath, ListenConfig{LockFile: true})
+	if err != nil {
+		t.Fatalf("ListenWithConfig() after release error = %v", err)
+	}
+	third.Close()
+}
+
+// fakeSocketActivation sets up the environment as if systemd had passed a
+// listening socket for socketPath to this process.
+func fakeSocketActivation(t *testing.T, socketPath string) {
+	t.Helper()
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	unixListener := listener.(*net.UnixListener)
+	unixListener.SetUnlinkOnClose(false)
+
+	file, err := unixListener.File()
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	// A bare descriptor, like the one systemd passes in
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatalf("Dup() error = %v", err)
+	}
+	file.Close()
+	listener.Close()
+
+	oldStart := listenFdsStart
+	listenFdsStart = fd
+	t.Cleanup(func() { listenFdsStart = oldStart })
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+}
+
+func TestActivationListener(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "activated.sock")
+	fakeSocketActivation(t, socketPath)
+
+	listener, err := activationListener()
+	if err != nil {
+		t.Fatalf("activationListener() error = %v", err)
+	}
+	if listener == nil {
+		t.Fatal("activationListener() = nil, want inherited listener")
+	}
+	defer listener.Close()
+
+	if os.Getenv("LISTEN_FDS") != "" || os.Getenv("LISTEN_PID") != "" {
+		t.Error("LISTEN_* variables should be cleared after adoption")
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	conn, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	conn.Close()
+}
+
+func TestActivationListener_OtherProcess(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listener, err := activationListener()
+	if err != nil || listener != nil {
+		t.Errorf("activationListener() = %v, %v; want nil, nil for another process", listener, err)
+	}
+}
+
+func TestServer_SocketActivation(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "activated.sock")
+	fakeSocketActivation(t, socketPath)
+
+	server, addr := startNetworkServer(t, ServerConfig{SocketPath: socketPath})
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial(%q) error = %v", addr, err)
+	}
+	result, err := callAdd(t, conn)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("callAdd() error = %v", err)
+	}
+	if result != 5 {
+		t.Errorf("Result = %v, want 5", result)
+	}
+
+	if err := server.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	// The socket belongs to systemd and must survive the server
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Errorf("Activated socket removed on Stop: %v", err)
+	}
+}
+
+// handoffChildEnv marks the test binary as the process started by Handoff.
+const handoffChildEnv = "IPC_JSONRPC_TEST_HANDOFF_CHILD"
+
+// TestHandoffChildProcess is not a real test: it is the new process started
+// by TestServer_Handoff. It serves "pid" on the inherited socket until killed.
+func TestHandoffChildProcess(t *testing.T) {
+	socketPath := os.Getenv(handoffChildEnv)
+	if socketPath == "" {
+		t.Skip("helper process for TestServer_Handoff")
+	}
+
+	server, err := NewServer(ServerConfig{SocketPath: socketPath, LockFile: true})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.RegisterFunc("pid", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return os.Getpid(), nil
+	})
+	server.Start()
+}
+
+// callPID asks the server behind conn for its process ID.
+func callPID(t *testing.T, conn net.Conn) int {
+	t.Helper()
+
+	codec := NewCodec(conn)
+	if err := codec.WriteJSON(&Request{JSONRPC: "2.0", Method: "pid", ID: 1}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var resp Response
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	pid, _ := resp.Result.(float64)
+	return int(pid)
+}
+
+func TestServer_Handoff(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "handoff.sock")
+
+	server, err := NewServer(ServerConfig{SocketPath: socketPath, LockFile: true})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.RegisterFunc("pid", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return os.Getpid(), nil
+	})
+	go server.Start()
+	defer server.Stop(context.Background())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Addr() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Server did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// An editor that stays connected across the upgrade
+	existing, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer existing.Close()
+	if pid := callPID(t, existing); pid != os.Getpid() {
+		t.Fatalf("pid = %d, want %d", pid, os.Getpid())
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHandoffChildProcess$")
+	cmd.Env = append(os.Environ(), handoffChildEnv+"="+socketPath)
+	defer func() {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	handoffDone := make(chan error, 1)
+	go func() { handoffDone <- server.Handoff(ctx, cmd) }()
+
+	// New connections reach the new process
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		conn, err := Dial(socketPath)
+		if err == nil {
+			pid := callPID(t, conn)
+			conn.Close()
+			if pid != 0 && pid != os.Getpid() {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("New process never accepted a connection")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// The existing connection is still served by the old process
+	if pid := callPID(t, existing); pid != os.Getpid() {
+		t.Errorf("Existing connection pid = %d, want %d", pid, os.Getpid())
+	}
+
+	select {
+	case err := <-handoffDone:
+		t.Fatalf("Handoff() returned %v before existing connections drained", err)
+	default:
+	}
+
+	existing.Close()
+	if err := <-handoffDone; err != nil {
+		t.Fatalf("Handoff() error = %v", err)
+	}
+
+	// The socket and lock survive the old server
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Errorf("Socket removed after handoff: %v", err)
+	}
+	if _, err := acquireLockFile(socketPath + ".lock"); !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("acquireLockFile() error = %v, want ErrAddressInUse (held by new process)", err)
+	}
+}
+
+func TestServer_HandoffNotListening(t *testing.T) {
+	server, err := NewServer(ServerConfig{SocketPath: filepath.Join(t.TempDir(), "idle.sock")})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	if err := server.Handoff(context.Background(), exec.Command("true")); err == nil {
+		t.Error("Handoff() on a server that is not listening should fail")
+	}
+}
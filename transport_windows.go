@@ -5,7 +5,9 @@ package jsonrpcipc
 import (
 	"fmt"
 	"net"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/Microsoft/go-winio"
 )
@@ -29,9 +31,19 @@ func normalizeWindowsPipePath(path string) string {
 	return fmt.Sprintf(`\\.\pipe\%s`, path)
 }
 
-// Listen creates a Windows Named Pipe listener
-func Listen(socketPath string) (net.Listener, error) {
+// listenLocal creates a Windows Named Pipe listener.
+// SocketMode and SocketGroup have no effect on Windows.
+//
+// If a server is already listening on the pipe, ErrAddressInUse is returned.
+func listenLocal(socketPath string, config ListenConfig) (net.Listener, error) {
 	addr := normalizeWindowsPipePath(socketPath)
+
+	timeout := time.Second
+	if conn, err := winio.DialPipe(addr, &timeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: a server is already listening on %s", ErrAddressInUse, addr)
+	}
+
 	listener, err := winio.ListenPipe(addr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create named pipe listener on %s: %w", addr, err)
@@ -39,8 +51,8 @@ func Listen(socketPath string) (net.Listener, error) {
 	return listener, nil
 }
 
-// Dial creates a Windows Named Pipe client connection
-func Dial(socketPath string) (net.Conn, error) {
+// dialLocal creates a Windows Named Pipe client connection
+func dialLocal(socketPath string) (net.Conn, error) {
 	addr := normalizeWindowsPipePath(socketPath)
 	conn, err := winio.DialPipe(addr, nil)
 	if err != nil {
@@ -49,14 +61,34 @@ func Dial(socketPath string) (net.Conn, error) {
 	return conn, nil
 }
 
-// CleanupSocket is a no-op on Windows as named pipes are automatically cleaned up.
-func CleanupSocket(socketPath string) error {
+// cleanupLocal is a no-op on Windows as named pipes are automatically cleaned up.
+func cleanupLocal(socketPath string) error {
 	// Named pipes are automatically cleaned up by Windows
 	return nil
 }
 
-// GetSocketPath returns the actual socket path that will be used by Listen/Dial.
-// This is useful for logging or displaying the socket path to users.
-func GetSocketPath(socketPath string) string {
+// localSocketPath returns the actual socket path that will be used by Listen/Dial.
+func localSocketPath(socketPath string) string {
 	return normalizeWindowsPipePath(socketPath)
 }
+
+// activationListener always returns nil on Windows, which has no socket activation.
+func activationListener() (net.Listener, error) {
+	return nil, nil
+}
+
+// listenerFiles always fails on Windows: named pipe handles cannot be passed
+// to another process the way Unix descriptors can.
+func listenerFiles(listener net.Listener) (socket, lock *os.File, err error) {
+	return nil, nil, fmt.Errorf("listener handoff is not supported on Windows")
+}
+
+// handoffEnv is never used on Windows, since listenerFiles always fails.
+func handoffEnv(socketFD, lockFD int, owned bool) []string {
+	return nil
+}
+
+// inheritedListener always returns nil on Windows.
+func inheritedListener() (listener net.Listener, owned bool, err error) {
+	return nil, false, nil
+}
@@ -26,6 +26,8 @@ type Request struct {
 	Method  string          `json:"method"`            // Method name to invoke
 	Params  json.RawMessage `json:"params,omitempty"`  // Method parameters (can be object or array)
 	ID      interface{}     `json:"id"`                // Request ID (string or number)
+	FDs     int             `json:"fds,omitempty"`     // Number of attached file descriptors (Unix sockets only)
+	Meta    Meta            `json:"meta,omitempty"`    // Envelope metadata such as trace context (extension)
 }
 
 // Response represents a JSON-RPC 2.0 success response.
@@ -41,6 +43,8 @@ type Response struct {
 	JSONRPC string      `json:"jsonrpc,omitempty"` // "2.0" (optional)
 	Result  interface{} `json:"result"`            // Result data
 	ID      interface{} `json:"id"`                // Request ID (must match request)
+	FDs     int         `json:"fds,omitempty"`     // Number of attached file descriptors (Unix sockets only)
+	Meta    Meta        `json:"meta,omitempty"`    // Envelope metadata (extension)
 }
 
 // ErrorResponse represents a JSON-RPC 2.0 error response.
@@ -60,6 +64,7 @@ type ErrorResponse struct {
 	JSONRPC string      `json:"jsonrpc,omitempty"` // "2.0" (optional)
 	Error   *RPCError   `json:"error"`             // Error object
 	ID      interface{} `json:"id"`                // Request ID (must match request, null if parse error)
+	Meta    Meta        `json:"meta,omitempty"`    // Envelope metadata (extension)
 }
 
 // RPCError represents a JSON-RPC 2.0 error object.
@@ -91,8 +96,16 @@ type Notification struct {
 	JSONRPC string      `json:"jsonrpc,omitempty"` // "2.0" (optional)
 	Method  string      `json:"method"`            // Notification method name
 	Params  interface{} `json:"params,omitempty"`  // Notification parameters
+	FDs     int         `json:"fds,omitempty"`     // Number of attached file descriptors (Unix sockets only)
+	Meta    Meta        `json:"meta,omitempty"`    // Envelope metadata such as trace context (extension)
 }
 
+// Meta is the optional "meta" member of a message envelope. It carries
+// data about the message rather than method parameters, such as the W3C
+// trace context ("traceparent" and "tracestate"). It is an extension to
+// JSON-RPC 2.0; peers that don't know it ignore it.
+type Meta map[string]interface{}
+
 // Message is a union type that can represent any JSON-RPC message.
 // Used for parsing incoming messages when the type is unknown.
 type Message struct {
@@ -102,6 +115,8 @@ type Message struct {
 	ID      interface{}     `json:"id,omitempty"`     // Present in requests/responses, absent in notifications
 	Result  json.RawMessage `json:"result,omitempty"` // Present in success responses
 	Error   *RPCError       `json:"error,omitempty"`  // Present in error responses
+	FDs     int             `json:"fds,omitempty"`    // Number of attached file descriptors (Unix sockets only)
+	Meta    Meta            `json:"meta,omitempty"`   // Envelope metadata (extension)
 }
 
 // IsRequest returns true if the message is a request (has method and ID).
@@ -140,6 +155,8 @@ func (m *Message) ToRequest() (*Request, error) {
 		Method:  m.Method,
 		Params:  m.Params,
 		ID:      m.ID,
+		FDs:     m.FDs,
+		Meta:    m.Meta,
 	}, nil
 }
 
@@ -161,5 +178,7 @@ func (m *Message) ToNotification() (*Notification, error) {
 		JSONRPC: m.JSONRPC,
 		Method:  m.Method,
 		Params:  params,
+		FDs:     m.FDs,
+		Meta:    m.Meta,
 	}, nil
 }
@@ -399,3 +399,24 @@ func TestMessage_IsErrorResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestMessage_MetaRoundTrip(t *testing.T) {
+	data := []byte(`{"jsonrpc":"2.0","method":"test","id":1,"meta":{"traceparent":"x","locale":"de"}}`)
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	req, err := msg.ToRequest()
+	if err != nil {
+		t.Fatalf("ToRequest() error = %v", err)
+	}
+	if req.Meta["locale"] != "de" || req.Meta["traceparent"] != "x" {
+		t.Errorf("Meta = %v, want traceparent and locale", req.Meta)
+	}
+
+	out, _ := json.Marshal(&Notification{JSONRPC: "2.0", Method: "n"})
+	if string(out) != `{"jsonrpc":"2.0","method":"n"}` {
+		t.Errorf("Notification without meta = %s, want no meta member", out)
+	}
+}
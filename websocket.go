@@ -0,0 +1,421 @@
+package jsonrpcipc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WebSocket transport (RFC 6455), implemented with the standard library only.
+//
+// Each WebSocket text or binary message carries one JSON-RPC message or batch.
+// WebSocket connections are served like socket connections, so they receive
+// Broadcast and Connection.Notify messages.
+
+// websocketGUID is the fixed GUID used to compute Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessageSize limits the size of a single incoming message.
+const maxWebSocketMessageSize = maxHTTPBodySize
+
+// WebSocket opcodes.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// WebSocket close status codes.
+const (
+	wsCloseNormal        = 1000
+	wsCloseProtocolError = 1002
+	wsCloseTooBig        = 1009
+)
+
+// errWebSocketProtocol is returned when the peer violates the framing rules.
+var errWebSocketProtocol = errors.New("websocket protocol error")
+
+// WebSocketHandler returns an http.Handler that upgrades requests to WebSocket
+// and serves JSON-RPC over them.
+//
+// HTTPHandler also accepts WebSocket upgrades, so a single endpoint can serve
+// POST, server-sent events and WebSocket clients.
+//
+// Example:
+//
+//	http.Handle("/ws", server.WebSocketHandler())
+func (s *Server) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(s.serveWebSocket)
+}
+
+// isWebSocketUpgrade reports whether r asks to be upgraded to WebSocket.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// headerContainsToken reports whether a comma-separated header contains token.
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin is the default origin check. Requests without an Origin header
+// (non-browser clients) are allowed; browsers must come from the same host,
+// which stops other web pages from talking to a local server.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// originAllowed applies ServerConfig.CheckOrigin, or sameOrigin.
+func (s *Server) originAllowed(r *http.Request) bool {
+	if s.config.CheckOrigin != nil {
+		return s.config.CheckOrigin(r)
+	}
+	return sameOrigin(r)
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept value for a client key.
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// serveWebSocket performs the opening handshake and serves the connection.
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !isWebSocketUpgrade(r) {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+
+	if !s.originAllowed(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	select {
+	case <-s.shutdownCh:
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		s.reportError(fmt.Errorf("websocket hijack error: %w", err))
+		return
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		netConn.Close()
+		return
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return
+	}
+
+	codec := newWebSocketCodec(netConn, rw.Reader, false)
+	conn := newCodecConnection(context.Background(), codec, netConn.RemoteAddr().String(), s.registry, s.middleware, s)
+	conn.conn = netConn
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	s.serveConnection(conn)
+}
+
+// webSocketCodec frames messages as WebSocket data frames.
+//
+// It implements both roles: servers expect masked frames from clients and
+// send unmasked frames; clients do the opposite.
+type webSocketCodec struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	client bool
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+
+	closeOnce sync.Once
+	closeSent atomic.Bool // a close frame may only be sent once
+}
+
+// newWebSocketCodec creates a codec for an upgraded connection. reader must
+// be the buffered reader used during the handshake, as it may already hold
+// frame data.
+func newWebSocketCodec(conn net.Conn, reader *bufio.Reader, client bool) *webSocketCodec {
+	if reader == nil {
+		reader = bufio.NewReader(conn)
+	}
+	return &webSocketCodec{
+		conn:   conn,
+		reader: reader,
+		client: client,
+	}
+}
+
+// readFrame reads a single frame and returns its FIN bit, opcode and
+// unmasked payload.
+func (c *webSocketCodec) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set", errWebSocketProtocol)
+	}
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, fmt.Errorf("%w: unexpected frame masking", errWebSocketProtocol)
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if opcode >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, fmt.Errorf("%w: invalid control frame", errWebSocketProtocol)
+	}
+	if length > maxWebSocketMessageSize {
+		return false, 0, nil, errWebSocketTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// errWebSocketTooBig is returned when an incoming message exceeds the size limit.
+var errWebSocketTooBig = fmt.Errorf("%w: message too big", errWebSocketProtocol)
+
+// writeFrame writes a single, final frame.
+func (c *webSocketCodec) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	header := make([]byte, 0, 14)
+	header = append(header, 0x80|opcode)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch length := len(payload); {
+	case length <= 125:
+		header = append(header, maskBit|byte(length))
+	case length <= 0xFFFF:
+		header = append(header, maskBit|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, maskBit|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		header = append(header, mask[:]...)
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// writeClose sends a close frame with the given status code. Nothing else is
+// written after it, so a short deadline stops a peer that doesn't read from
+// blocking the caller. Only the first call sends a frame, as RFC 6455
+// allows one close frame per endpoint.
+func (c *webSocketCodec) writeClose(code uint16) error {
+	if !c.closeSent.CompareAndSwap(false, true) {
+		return nil
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	return c.writeFrame(wsOpClose, binary.BigEndian.AppendUint16(nil, code))
+}
+
+// ReadMessage returns the next complete data message.
+// Ping frames are answered automatically; a close frame ends the stream with io.EOF.
+func (c *webSocketCodec) ReadMessage() ([]byte, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	var message []byte
+	started := false
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errWebSocketTooBig) {
+				c.writeClose(wsCloseTooBig)
+			} else if errors.Is(err, errWebSocketProtocol) {
+				c.writeClose(wsCloseProtocolError)
+			}
+			return nil, fmt.Errorf("read error: %w", err)
+		}
+
+		switch opcode {
+		case wsOpPing:
+			c.writeFrame(wsOpPong, payload)
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			c.writeClose(wsCloseNormal)
+			return nil, fmt.Errorf("read error: %w", io.EOF)
+		case wsOpText, wsOpBinary:
+			if started {
+				c.writeClose(wsCloseProtocolError)
+				return nil, fmt.Errorf("read error: %w: expected continuation frame", errWebSocketProtocol)
+			}
+			started = true
+		case wsOpContinuation:
+			if !started {
+				c.writeClose(wsCloseProtocolError)
+				return nil, fmt.Errorf("read error: %w: unexpected continuation frame", errWebSocketProtocol)
+			}
+		default:
+			c.writeClose(wsCloseProtocolError)
+			return nil, fmt.Errorf("read error: %w: unknown opcode %d", errWebSocketProtocol, opcode)
+		}
+
+		if len(message)+len(payload) > maxWebSocketMessageSize {
+			c.writeClose(wsCloseTooBig)
+			return nil, fmt.Errorf("read error: %w", errWebSocketTooBig)
+		}
+		message = append(message, payload...)
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single text message.
+func (c *webSocketCodec) WriteMessage(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+// ReadJSON reads and unmarshals the next message.
+func (c *webSocketCodec) ReadJSON(v interface{}) error {
+	data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("json unmarshal error: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON marshals v and sends it as a single text message.
+func (c *webSocketCodec) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %w", err)
+	}
+	return c.WriteMessage(data)
+}
+
+// Close sends a close frame (best effort) unless ReadMessage already sent
+// one, and closes the connection.
+func (c *webSocketCodec) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.writeClose(wsCloseNormal)
+		err = c.conn.Close()
+	})
+	return err
+}
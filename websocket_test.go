@@ -0,0 +1,404 @@
+package jsonrpcipc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialWebSocket performs a client handshake against url and returns a
+// client-side codec. It fails the test unless the server answers 101.
+func dialWebSocket(t *testing.T, url string, header http.Header) *webSocketCodec {
+	t.Helper()
+
+	conn, resp := webSocketHandshake(t, url, header)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Handshake status = %d, want 101", resp.StatusCode)
+	}
+	return conn
+}
+
+// webSocketHandshake sends an upgrade request and returns the codec and the
+// server's response.
+func webSocketHandshake(t *testing.T, url string, header http.Header) (*webSocketCodec, *http.Response) {
+	t.Helper()
+
+	host := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("Write handshake error = %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("ReadResponse() error = %v", err)
+	}
+
+	codec := newWebSocketCodec(conn, br, true)
+	t.Cleanup(func() { codec.Close() })
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		if got := resp.Header.Get("Sec-WebSocket-Accept"); got != websocketAccept(key) {
+			t.Errorf("Sec-WebSocket-Accept = %q, want %q", got, websocketAccept(key))
+		}
+	}
+	return codec, resp
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	// Example from RFC 6455, section 1.3
+	if got := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("websocketAccept() = %q, want s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", got)
+	}
+}
+
+func TestWebSocket_Request(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	if err := conn.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"add","params":{"A":2,"B":3},"id":1}`)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	var resp Response
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Result != float64(5) {
+		t.Errorf("Result = %v, want 5", resp.Result)
+	}
+	if !compareIDs(resp.ID, 1) {
+		t.Errorf("ID = %v, want 1", resp.ID)
+	}
+}
+
+func TestWebSocket_Batch(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	conn.WriteMessage([]byte(`[
+		{"jsonrpc":"2.0","method":"add","params":{"A":1,"B":1},"id":1},
+		{"jsonrpc":"2.0","method":"add","params":{"A":2,"B":2},"id":2}
+	]`))
+
+	var replies []Message
+	if err := conn.ReadJSON(&replies); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if len(replies) != 2 {
+		t.Fatalf("Batch replies = %d, want 2", len(replies))
+	}
+	if string(replies[1].Result) != "4" {
+		t.Errorf("Second result = %s, want 4", replies[1].Result)
+	}
+}
+
+func TestWebSocket_BroadcastAndNotify(t *testing.T) {
+	server, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for server.ConnectionCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if count := server.Broadcast("build.done", nil); count != 1 {
+		t.Errorf("Broadcast() count = %d, want 1", count)
+	}
+
+	var notif Notification
+	if err := conn.ReadJSON(&notif); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if notif.Method != "build.done" {
+		t.Errorf("Notification method = %q, want build.done", notif.Method)
+	}
+
+	// Handler notifications arrive before the response
+	conn.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifyMe","id":2}`))
+	if err := conn.ReadJSON(&notif); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if notif.Method != "progress" {
+		t.Errorf("Notification method = %q, want progress", notif.Method)
+	}
+}
+
+func TestWebSocket_PingPong(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	if err := conn.writeFrame(wsOpPing, []byte("hello")); err != nil {
+		t.Fatalf("writeFrame() error = %v", err)
+	}
+
+	_, opcode, payload, err := conn.readFrame()
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if opcode != wsOpPong || string(payload) != "hello" {
+		t.Errorf("Reply = opcode %d payload %q, want pong \"hello\"", opcode, payload)
+	}
+}
+
+func TestWebSocket_Fragmented(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	msg := []byte(`{"jsonrpc":"2.0","method":"add","params":{"A":4,"B":5},"id":3}`)
+
+	// Send the message in two frames: text without FIN, then continuation with FIN
+	frames := []struct {
+		header byte
+		data   []byte
+	}{
+		{wsOpText, msg[:10]},
+		{0x80 | wsOpContinuation, msg[10:]},
+	}
+	for _, f := range frames {
+		frame := []byte{f.header, 0x80 | byte(len(f.data)), 0, 0, 0, 0}
+		frame = append(frame, f.data...)
+		if _, err := conn.conn.Write(frame); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	var resp Response
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Result != float64(9) {
+		t.Errorf("Result = %v, want 9", resp.Result)
+	}
+}
+
+func TestWebSocket_ClientClose(t *testing.T) {
+	server, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for server.ConnectionCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	conn.Close()
+
+	deadline = time.Now().Add(time.Second)
+	for server.ConnectionCount() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if server.ConnectionCount() != 0 {
+		t.Errorf("ConnectionCount() after close = %d, want 0", server.ConnectionCount())
+	}
+}
+
+func TestWebSocket_ServerStop(t *testing.T) {
+	server, httpServer := newHTTPTestServer(t)
+	conn := dialWebSocket(t, httpServer.URL, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for server.ConnectionCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	// The server sends a close frame before closing the connection
+	_, opcode, _, err := conn.readFrame()
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if opcode != wsOpClose {
+		t.Errorf("Opcode = %d, want close", opcode)
+	}
+}
+
+func TestWebSocket_Origin(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+	host := strings.TrimPrefix(httpServer.URL, "http://")
+
+	tests := []struct {
+		name   string
+		origin string
+		want   int
+	}{
+		{"no origin", "", http.StatusSwitchingProtocols},
+		{"same origin", "http://" + host, http.StatusSwitchingProtocols},
+		{"cross origin", "http://evil.example", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			_, resp := webSocketHandshake(t, httpServer.URL, header)
+			if resp.StatusCode != tt.want {
+				t.Errorf("Status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocket_CheckOrigin(t *testing.T) {
+	server, err := NewServer(ServerConfig{
+		SocketPath: "test-ws-origin",
+		CheckOrigin: func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "app://ui"
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	httpServer := httptest.NewServer(server.WebSocketHandler())
+	defer httpServer.Close()
+
+	_, resp := webSocketHandshake(t, httpServer.URL, http.Header{"Origin": []string{"app://ui"}})
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Allowed origin status = %d, want 101", resp.StatusCode)
+	}
+
+	_, resp = webSocketHandshake(t, httpServer.URL, http.Header{"Origin": []string{"http://" + httpServer.Listener.Addr().String()}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Rejected origin status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestWebSocket_BadVersion(t *testing.T) {
+	_, httpServer := newHTTPTestServer(t)
+
+	_, resp := webSocketHandshake(t, httpServer.URL, http.Header{"Sec-Websocket-Version": []string{"8"}})
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("Status = %d, want 426", resp.StatusCode)
+	}
+	if v := resp.Header.Get("Sec-WebSocket-Version"); v != "13" {
+		t.Errorf("Sec-WebSocket-Version = %q, want 13", v)
+	}
+}
+
+func TestWebSocketHandler_RequiresUpgrade(t *testing.T) {
+	server, err := NewServer(ServerConfig{SocketPath: "test-ws-plain"})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	httpServer := httptest.NewServer(server.WebSocketHandler())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("Status = %d, want 426", resp.StatusCode)
+	}
+}
+
+func TestWebSocketCodec_UnmaskedClientFrame(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	codec := newWebSocketCodec(server, nil, false)
+	defer codec.Close()
+
+	go func() {
+		// Clients must mask their frames
+		client.Write([]byte{0x80 | wsOpText, 2, '{', '}'})
+		io.Copy(io.Discard, client)
+	}()
+
+	if _, err := codec.ReadMessage(); err == nil {
+		t.Error("ReadMessage() should reject an unmasked client frame")
+	}
+}
+
+func TestWebSocketCodec_RoundTrip(t *testing.T) {
+	a, b := net.Pipe()
+	client := newWebSocketCodec(a, nil, true)
+	server := newWebSocketCodec(b, nil, false)
+	defer client.Close()
+	defer server.Close()
+
+	// Large enough to use the 64-bit length encoding
+	big := strings.Repeat("x", 70000)
+
+	go client.WriteJSON(map[string]string{"data": big})
+
+	var got map[string]string
+	data, err := server.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got["data"] != big {
+		t.Errorf("Round-tripped data length = %d, want %d", len(got["data"]), len(big))
+	}
+}
+
+func TestWebSocketCodec_SingleCloseFrame(t *testing.T) {
+	a, b := net.Pipe()
+	client := newWebSocketCodec(a, nil, true)
+	server := newWebSocketCodec(b, nil, false)
+	defer client.Close()
+
+	opcodes := make(chan []byte, 1)
+	go func() {
+		var got []byte
+		client.writeClose(wsCloseNormal)
+		for {
+			_, opcode, _, err := client.readFrame()
+			if err != nil {
+				break
+			}
+			got = append(got, opcode)
+		}
+		opcodes <- got
+	}()
+
+	if _, err := server.ReadMessage(); !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadMessage() after a close frame error = %v, want EOF", err)
+	}
+	server.Close()
+
+	// The reply to the client's close frame is the only one
+	if got := <-opcodes; len(got) != 1 || got[0] != wsOpClose {
+		t.Errorf("Frames from the server = %v, want a single close", got)
+	}
+}
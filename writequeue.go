@@ -0,0 +1,239 @@
+package jsonrpcipc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultWriteQueueSize is the number of notifications a connection buffers
+// for a slow client when ServerConfig.WriteQueueSize is zero.
+const DefaultWriteQueueSize = 256
+
+// QueuePolicy selects what happens when a notification is sent to a
+// connection whose write queue is full, that is, whose client reads slower
+// than the server writes.
+//
+// Responses are never dropped: they are queued in order with the
+// notifications and bounded separately. Once a connection has as many
+// unsent responses as the queue size, the server stops reading its requests
+// until the client catches up.
+type QueuePolicy int
+
+const (
+	// QueueDropOldest discards the oldest queued notification to make room.
+	// Suits state updates where only the latest value matters. It is the
+	// default, so one stuck client never stalls a Broadcast to the others.
+	QueueDropOldest QueuePolicy = iota
+
+	// QueueDropNewest discards the notification being sent, and the send
+	// returns ErrWriteQueueFull.
+	QueueDropNewest
+
+	// QueueDisconnect closes the connection, so the client can reconnect and
+	// resynchronize instead of missing messages silently. The send returns
+	// ErrWriteQueueFull.
+	QueueDisconnect
+
+	// QueueBlock makes the sender wait until the queue has room.
+	// No message is lost, but a stuck client stalls the sender, including
+	// a Broadcast to every other client.
+	QueueBlock
+)
+
+// String returns the policy name.
+func (p QueuePolicy) String() string {
+	switch p {
+	case QueueDropOldest:
+		return "drop-oldest"
+	case QueueDropNewest:
+		return "drop-newest"
+	case QueueDisconnect:
+		return "disconnect"
+	case QueueBlock:
+		return "block"
+	default:
+		return fmt.Sprintf("QueuePolicy(%d)", int(p))
+	}
+}
+
+// ErrWriteQueueFull is returned when a notification is dropped because the
+// connection's write queue is full (see QueueDropNewest and QueueDisconnect).
+var ErrWriteQueueFull = errors.New("write queue is full")
+
+// errConnectionClosed is returned when sending on a closed connection.
+var errConnectionClosed = errors.New("connection is closed")
+
+// outbound is a message waiting in a write queue.
+type outbound struct {
+	data  []byte
+	files []*os.File
+
+	// droppable is set for notifications, which the overflow policy applies
+	// to. Responses and messages with files are never dropped.
+	droppable bool
+
+	// reply is set for responses, which wait while the queue holds size
+	// unsent responses, so a client that never reads them stops being read.
+	reply bool
+
+	// ownsFiles is set when the queue must close files once they are sent
+	// (handler results); otherwise the sender keeps ownership.
+	ownsFiles bool
+
+	// done, if set, receives the result of the write.
+	done chan error
+}
+
+// finish reports the result of writing m and releases its files.
+func (m *outbound) finish(err error) {
+	if m.ownsFiles {
+		closeFiles(m.files)
+	}
+	if m.done != nil {
+		m.done <- err
+	}
+}
+
+// writeQueue is a connection's outbound queue. Senders push messages and
+// a single writer goroutine pops and writes them in order, so a slow client
+// holds up only its own queue.
+type writeQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // Signaled when items, room or the state changes
+	items   []*outbound
+	queued  int // Droppable items in items, bounded by size
+	replies int // Reply items in items, bounded by size
+	size    int
+	policy  QueuePolicy
+
+	draining bool // No new messages; the writer exits once empty
+	closed   bool // Pending messages discarded; the writer exits
+
+	dropped atomic.Uint64
+	onDrop  func() // Called for each dropped message; optional
+}
+
+// newWriteQueue creates a queue holding up to size notifications.
+func newWriteQueue(size int, policy QueuePolicy) *writeQueue {
+	if size <= 0 {
+		size = DefaultWriteQueueSize
+	}
+	q := &writeQueue{size: size, policy: policy}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds m to the queue, applying the overflow policy to notifications
+// and waiting for room for replies. It returns ErrWriteQueueFull if m was
+// dropped; with QueueDisconnect the caller then closes the connection.
+func (q *writeQueue) push(m *outbound) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.stopped() && (m.droppable && q.queued >= q.size && q.policy == QueueBlock ||
+		m.reply && q.replies >= q.size) {
+		q.cond.Wait()
+	}
+	if q.stopped() {
+		return errConnectionClosed
+	}
+
+	if m.droppable && q.queued >= q.size {
+		switch q.policy {
+		case QueueDropOldest:
+			q.dropOldest()
+		default:
+			q.drop()
+			return ErrWriteQueueFull
+		}
+	}
+
+	q.items = append(q.items, m)
+	if m.droppable {
+		q.queued++
+	}
+	if m.reply {
+		q.replies++
+	}
+	q.cond.Broadcast()
+	return nil
+}
+
+// dropOldest removes the oldest queued notification. q.mu must be held.
+func (q *writeQueue) dropOldest() {
+	for i, m := range q.items {
+		if m.droppable {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.queued--
+			q.drop()
+			return
+		}
+	}
+}
+
+// drop counts a dropped message. q.mu must be held.
+func (q *writeQueue) drop() {
+	q.dropped.Add(1)
+	if q.onDrop != nil {
+		q.onDrop()
+	}
+}
+
+// pop waits for the next message. It returns nil once the queue is closed,
+// or drained and empty.
+func (q *writeQueue) pop() *outbound {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.stopped() {
+		q.cond.Wait()
+	}
+	if q.closed || len(q.items) == 0 {
+		return nil
+	}
+
+	m := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	if m.droppable {
+		q.queued--
+	}
+	if m.reply {
+		q.replies--
+	}
+	q.cond.Broadcast()
+	return m
+}
+
+// drain stops accepting messages; the writer exits after sending those
+// already queued.
+func (q *writeQueue) drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.draining = true
+	q.cond.Broadcast()
+}
+
+// close stops the queue and discards pending messages.
+func (q *writeQueue) close() {
+	q.mu.Lock()
+	pending := q.items
+	q.items = nil
+	q.queued = 0
+	q.replies = 0
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	for _, m := range pending {
+		m.finish(errConnectionClosed)
+	}
+}
+
+// stopped reports whether the queue accepts no more messages. q.mu must be held.
+func (q *writeQueue) stopped() bool {
+	return q.closed || q.draining
+}
@@ -0,0 +1,321 @@
+package jsonrpcipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// newStalledConnection returns a connection whose client never reads, so
+// everything sent to it piles up in the write queue.
+func newStalledConnection(t *testing.T, size int, policy QueuePolicy) (*Connection, *mockConn) {
+	t.Helper()
+
+	conn1, conn2 := newMockConnPair()
+	t.Cleanup(func() {
+		conn1.Close()
+		conn2.Close()
+	})
+
+	server, err := NewServer(ServerConfig{
+		SocketPath:       "tcp://127.0.0.1:0",
+		WriteQueueSize:   size,
+		WriteQueuePolicy: policy,
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	connection := newConnection(conn1, NewHandlerRegistry(), nil, server)
+	t.Cleanup(func() { connection.Close() })
+	return connection, conn2
+}
+
+// readMethods reads n messages from the client side and returns their methods
+// (or "id" for responses).
+func readMethods(t *testing.T, peer *mockConn, n int) []string {
+	t.Helper()
+
+	codec := NewCodec(peer)
+	methods := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		var msg Message
+		if err := codec.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		if msg.Method == "" {
+			methods = append(methods, "id")
+			continue
+		}
+		methods = append(methods, msg.Method)
+	}
+	return methods
+}
+
+func TestQueuePolicy_String(t *testing.T) {
+	tests := map[QueuePolicy]string{
+		QueueBlock:      "block",
+		QueueDropOldest: "drop-oldest",
+		QueueDropNewest: "drop-newest",
+		QueueDisconnect: "disconnect",
+		QueuePolicy(9):  "QueuePolicy(9)",
+	}
+	for policy, want := range tests {
+		if got := policy.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestWriteQueue_DropNewest(t *testing.T) {
+	connection, peer := newStalledConnection(t, 2, QueueDropNewest)
+
+	// The writer takes the first message and stalls writing it; two more fill the queue
+	for _, method := range []string{"a", "b", "c"} {
+		if err := connection.Notify(method, nil); err != nil {
+			t.Fatalf("Notify(%q) error = %v", method, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := connection.Notify("d", nil); !errors.Is(err, ErrWriteQueueFull) {
+		t.Fatalf("Notify() on a full queue error = %v, want ErrWriteQueueFull", err)
+	}
+	if got := connection.DroppedMessages(); got != 1 {
+		t.Errorf("DroppedMessages() = %d, want 1", got)
+	}
+	if got := connection.server.metrics.droppedMessages.Load(); got != 1 {
+		t.Errorf("dropped messages metric = %d, want 1", got)
+	}
+
+	got := readMethods(t, peer, 3)
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Received %v, want [a b c]", got)
+	}
+}
+
+func TestWriteQueue_DropOldest(t *testing.T) {
+	connection, peer := newStalledConnection(t, 2, QueueDropOldest)
+
+	for _, method := range []string{"a", "b", "c", "d"} {
+		if err := connection.Notify(method, nil); err != nil {
+			t.Fatalf("Notify(%q) error = %v", method, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := connection.DroppedMessages(); got != 1 {
+		t.Errorf("DroppedMessages() = %d, want 1", got)
+	}
+
+	// "a" was already being written; "b" was the oldest queued
+	got := readMethods(t, peer, 3)
+	if got[0] != "a" || got[1] != "c" || got[2] != "d" {
+		t.Errorf("Received %v, want [a c d]", got)
+	}
+}
+
+func TestWriteQueue_Disconnect(t *testing.T) {
+	connection, _ := newStalledConnection(t, 1, QueueDisconnect)
+
+	for _, method := range []string{"a", "b"} {
+		if err := connection.Notify(method, nil); err != nil {
+			t.Fatalf("Notify(%q) error = %v", method, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := connection.Notify("c", nil); !errors.Is(err, ErrWriteQueueFull) {
+		t.Fatalf("Notify() on a full queue error = %v, want ErrWriteQueueFull", err)
+	}
+	if !connection.IsClosed() {
+		t.Error("Connection should be closed after its queue overflowed")
+	}
+	if err := connection.Notify("d", nil); err == nil {
+		t.Error("Notify() after disconnect should fail")
+	}
+}
+
+func TestWriteQueue_DefaultPolicyBroadcast(t *testing.T) {
+	server, err := NewServer(ServerConfig{SocketPath: "tcp://127.0.0.1:0", WriteQueueSize: 2})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	// One client never reads, the other reads everything
+	peers := make([]*mockConn, 2)
+	for i := range peers {
+		conn1, conn2 := newMockConnPair()
+		connection := newConnection(conn1, server.registry, nil, server)
+		t.Cleanup(func() {
+			connection.Close()
+			conn2.Close()
+		})
+		server.broadcast.Add(connection)
+		peers[i] = conn2
+	}
+	go io.Copy(io.Discard, peers[1])
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			server.Broadcast("tick", i)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Broadcast() stalled on a client that never reads")
+	}
+}
+
+func TestWriteQueue_Block(t *testing.T) {
+	connection, peer := newStalledConnection(t, 1, QueueBlock)
+
+	for _, method := range []string{"a", "b"} {
+		if err := connection.Notify(method, nil); err != nil {
+			t.Fatalf("Notify(%q) error = %v", method, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sent := make(chan error, 1)
+	go func() { sent <- connection.Notify("c", nil) }()
+
+	select {
+	case err := <-sent:
+		t.Fatalf("Notify() on a full queue returned %v, want it to block", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	got := readMethods(t, peer, 3)
+	if err := <-sent; err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Received %v, want [a b c]", got)
+	}
+	if dropped := connection.DroppedMessages(); dropped != 0 {
+		t.Errorf("DroppedMessages() = %d, want 0", dropped)
+	}
+}
+
+func TestWriteQueue_BlockUnblockedByClose(t *testing.T) {
+	connection, _ := newStalledConnection(t, 1, QueueBlock)
+
+	for _, method := range []string{"a", "b"} {
+		connection.Notify(method, nil)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sent := make(chan error, 1)
+	go func() { sent <- connection.Notify("c", nil) }()
+	time.Sleep(20 * time.Millisecond)
+
+	connection.Close()
+
+	select {
+	case err := <-sent:
+		if err == nil {
+			t.Error("Notify() blocked on a closed connection should fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Notify() still blocked after Close()")
+	}
+}
+
+func TestWriteQueue_ResponsesNotDropped(t *testing.T) {
+	connection, peer := newStalledConnection(t, 1, QueueDropNewest)
+
+	for _, method := range []string{"a", "b"} {
+		connection.Notify(method, nil)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The queue is full of notifications, but a response is still queued, after them
+	if err := connection.sendResult(1, "ok"); err != nil {
+		t.Fatalf("sendResult() error = %v", err)
+	}
+	if err := connection.Notify("c", nil); !errors.Is(err, ErrWriteQueueFull) {
+		t.Fatalf("Notify() error = %v, want ErrWriteQueueFull", err)
+	}
+
+	got := readMethods(t, peer, 3)
+	if got[0] != "a" || got[1] != "b" || got[2] != "id" {
+		t.Errorf("Received %v, want [a b id]", got)
+	}
+}
+
+func TestWriteQueue_RepliesBounded(t *testing.T) {
+	connection, peer := newStalledConnection(t, 1, QueueDropNewest)
+
+	// The first reply is being written, the second fills the queue
+	for id := 1; id <= 2; id++ {
+		if err := connection.sendResult(id, "ok"); err != nil {
+			t.Fatalf("sendResult() error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The third waits for the client to read, instead of growing the queue
+	done := make(chan error, 1)
+	go func() { done <- connection.sendResult(3, "ok") }()
+	select {
+	case err := <-done:
+		t.Fatalf("sendResult() on a full queue returned %v, want it to wait", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	readMethods(t, peer, 3)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("sendResult() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("sendResult() still waiting after the client read")
+	}
+}
+
+func TestWriteQueue_FlushOnClientClose(t *testing.T) {
+	conn1, conn2 := newMockConnPair()
+	defer conn2.Close()
+
+	registry := NewHandlerRegistry()
+	registry.RegisterFunc("echo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "ok", nil
+	})
+	connection := newConnection(conn1, registry, nil, nil)
+
+	done := make(chan struct{})
+	go func() {
+		connection.Serve()
+		close(done)
+	}()
+
+	// Send a request and stop writing, as a client half-closing its socket does
+	codec := NewCodec(conn2)
+	if err := codec.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"echo","id":1}`)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	conn2.writer.Close()
+
+	var resp Response
+	if err := codec.ReadJSON(&resp); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if resp.Result != "ok" {
+		t.Errorf("Result = %v, want ok", resp.Result)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve() did not return")
+	}
+}